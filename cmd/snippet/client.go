@@ -0,0 +1,153 @@
+package main
+
+import (
+    "bytes"
+    "crypto/tls"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "sort"
+    "strings"
+    "time"
+)
+
+// snippet is a snippet as sent by the API. Content is nil for snippets
+// locked with a passphrase that belong to someone else
+type snippet struct {
+    Slug             string     `json:"slug"`
+    Title            string     `json:"title"`
+    Content          *string    `json:"content"`
+    Language         string     `json:"language"`
+    Visibility       string     `json:"visibility"`
+    Created          time.Time  `json:"created"`
+    Expires          *time.Time `json:"expires"`
+    BurnAfterReading bool       `json:"burn_after_reading"`
+    Locked           bool       `json:"locked"`
+    Author           string     `json:"author"`
+    URL              string     `json:"url"`
+}
+
+// snippetList is a page of snippets as sent by the API
+type snippetList struct {
+    Snippets []snippet `json:"snippets"`
+    Next     string    `json:"next"`
+}
+
+// apiError is the error envelope of the API
+type apiError struct {
+    Status  int               `json:"-"`
+    Message string            `json:"error"`
+    Errors  map[string]string `json:"errors"`
+}
+
+// Error returns the message from the server, followed by the problem with
+// each field of the request, if there were any
+func (e *apiError) Error() string {
+    if len(e.Errors) == 0 {
+        return e.Message
+    }
+
+    fields := make([]string, 0, len(e.Errors))
+    for field := range e.Errors {
+        fields = append(fields, field)
+    }
+    sort.Strings(fields)
+
+    var b strings.Builder
+    b.WriteString(e.Message)
+    for _, field := range fields {
+        fmt.Fprintf(&b, "\n  %s: %s", field, e.Errors[field])
+    }
+
+    return b.String()
+}
+
+// client makes requests to the API of a snippetbox server
+type client struct {
+    server string
+    token  string
+    http   *http.Client
+}
+
+// newClient returns a client for the server in cfg
+func newClient(cfg config) *client {
+    transport := http.DefaultTransport.(*http.Transport).Clone()
+    if cfg.Insecure {
+        transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+    }
+
+    return &client{
+        server: cfg.Server,
+        token:  cfg.Token,
+        http:   &http.Client{Transport: transport, Timeout: 30 * time.Second},
+    }
+}
+
+// do sends a request to the API path, with in encoded as JSON as its body
+// unless it is nil, and decodes the JSON response into out unless it is
+// nil. Error responses are returned as an *apiError
+func (c *client) do(method, path string, in any, out any) error {
+    var body io.Reader
+    if in != nil {
+        js, err := json.Marshal(in)
+        if err != nil {
+            return err
+        }
+        body = bytes.NewReader(js)
+    }
+
+    req, err := http.NewRequest(method, c.server+path, body)
+    if err != nil {
+        return err
+    }
+
+    req.Header.Set("Accept", "application/json")
+    if in != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    if c.token != "" {
+        req.Header.Set("Authorization", "Bearer "+c.token)
+    }
+
+    rs, err := c.http.Do(req)
+    if err != nil {
+        return err
+    }
+    defer rs.Body.Close()
+
+    if rs.StatusCode >= 400 {
+        e := &apiError{Status: rs.StatusCode}
+
+        // anything in front of the server, such as a proxy, may send
+        // errors that are not in the API's envelope
+        err = json.NewDecoder(rs.Body).Decode(e)
+        if err != nil || e.Message == "" {
+            e.Message = http.StatusText(rs.StatusCode)
+        }
+
+        if rs.StatusCode == http.StatusUnauthorized {
+            e.Message += ` (run "snippet login" with a valid API token)`
+        }
+
+        return e
+    }
+
+    if out == nil {
+        return nil
+    }
+
+    err = json.NewDecoder(rs.Body).Decode(out)
+    if err != nil {
+        return fmt.Errorf("reading the response from %s: %w", c.server, err)
+    }
+
+    return nil
+}
+
+// isStatus reports whether err is an error response with the given status
+func isStatus(err error, status int) bool {
+    var e *apiError
+    return errors.As(err, &e) && e.Status == status
+}
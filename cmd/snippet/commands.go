@@ -0,0 +1,224 @@
+package main
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "os"
+    "path"
+    "path/filepath"
+    "strings"
+    "text/tabwriter"
+)
+
+// login saves the server to use and an API token for it. The token is read
+// from standard input rather than taken as an argument, so that it does not
+// end up in the shell's history. The environment is left out, so that an
+// override meant for one command is never saved for all of them
+func (c *cli) login(args []string) error {
+    cfg, err := c.readConfig()
+    if err != nil {
+        return err
+    }
+
+    fs := c.flagSet("login", "[-server URL] [-insecure] < token")
+    fs.StringVar(&cfg.Server, "server", cfg.Server, "URL of the snippetbox server")
+    fs.BoolVar(&cfg.Insecure, "insecure", cfg.Insecure, "Do not check the server's TLS certificate")
+
+    err = parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    fmt.Fprintf(c.stderr, "Create a token on the account page of %s/account and paste it here.\nAPI token: ", strings.TrimRight(cfg.Server, "/"))
+
+    line, err := bufio.NewReader(c.stdin).ReadString('\n')
+    if err != nil && !errors.Is(err, io.EOF) {
+        return err
+    }
+
+    cfg.Server = strings.TrimRight(cfg.Server, "/")
+    cfg.Token = strings.TrimSpace(line)
+    if cfg.Token == "" {
+        return errors.New("no API token given")
+    }
+
+    // anonymous requests can list snippets too, but a bad token is refused,
+    // so this checks both the server and the token
+    err = newClient(cfg).do(http.MethodGet, "/api/v1/snippets", nil, nil)
+    if err != nil {
+        return err
+    }
+
+    err = c.saveConfig(cfg)
+    if err != nil {
+        return err
+    }
+
+    fmt.Fprintf(c.stderr, "Logged in to %s\n", cfg.Server)
+    return nil
+}
+
+// create makes a snippet from the file named on the command line, or from
+// standard input if there is none, and prints the URL it can be viewed at
+func (c *cli) create(args []string) error {
+    var req struct {
+        Title      string `json:"title"`
+        Content    string `json:"content"`
+        Language   string `json:"language,omitempty"`
+        Visibility string `json:"visibility,omitempty"`
+        Expires    string `json:"expires,omitempty"`
+        Burn       bool   `json:"burn,omitempty"`
+    }
+
+    fs := c.flagSet("create", "[flags] [file]")
+    fs.StringVar(&req.Title, "t", "", "Title (defaults to the name of the file)")
+    fs.StringVar(&req.Expires, "e", "", "When the snippet expires, such as 36h, 7d, 2w, 6mo, 1y or never (default 1y)")
+    fs.StringVar(&req.Language, "l", "", "Language for syntax highlighting (detected from the content by default)")
+    fs.StringVar(&req.Visibility, "v", "", "Visibility: public, unlisted or private (default public)")
+    fs.BoolVar(&req.Burn, "burn", false, "Destroy the snippet after it is read once")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+    if fs.NArg() > 1 {
+        fs.Usage()
+        return errUsage
+    }
+
+    var content []byte
+    if file := fs.Arg(0); file != "" && file != "-" {
+        content, err = os.ReadFile(file)
+        if req.Title == "" {
+            req.Title = filepath.Base(file)
+        }
+    } else {
+        content, err = io.ReadAll(c.stdin)
+    }
+    if err != nil {
+        return err
+    }
+    req.Content = string(content)
+
+    cfg, err := c.loadConfig()
+    if err != nil {
+        return err
+    }
+
+    var s snippet
+    err = newClient(cfg).do(http.MethodPost, "/api/v1/snippets", req, &s)
+    if err != nil {
+        return err
+    }
+
+    fmt.Fprintln(c.stdout, cfg.Server+s.URL)
+    return nil
+}
+
+// get prints the content of a snippet exactly as it was saved, so that it
+// can be piped into another program or a file
+func (c *cli) get(args []string) error {
+    fs := c.flagSet("get", "<slug or URL>")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+    if fs.NArg() != 1 {
+        fs.Usage()
+        return errUsage
+    }
+
+    slug := snippetSlug(fs.Arg(0))
+
+    cfg, err := c.loadConfig()
+    if err != nil {
+        return err
+    }
+
+    var s snippet
+    err = newClient(cfg).do(http.MethodGet, "/api/v1/snippets/"+url.PathEscape(slug), nil, &s)
+    if err != nil {
+        if isStatus(err, http.StatusNotFound) {
+            return fmt.Errorf("snippet %s not found", slug)
+        }
+        return err
+    }
+
+    if s.Content == nil {
+        return fmt.Errorf("snippet %s is locked with a passphrase; unlock it at %s%s", slug, cfg.Server, s.URL)
+    }
+
+    _, err = io.WriteString(c.stdout, *s.Content)
+    return err
+}
+
+// list prints the latest public snippets
+func (c *cli) list(args []string) error {
+    fs := c.flagSet("list", "")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+    if fs.NArg() != 0 {
+        fs.Usage()
+        return errUsage
+    }
+
+    return c.printSnippets("/api/v1/snippets")
+}
+
+// search prints the public snippets matching a query, best match first
+func (c *cli) search(args []string) error {
+    fs := c.flagSet("search", "<query>")
+
+    err := parse(fs, args)
+    if err != nil {
+        return err
+    }
+
+    query := strings.Join(fs.Args(), " ")
+    if strings.TrimSpace(query) == "" {
+        fs.Usage()
+        return errUsage
+    }
+
+    return c.printSnippets("/api/v1/snippets?" + url.Values{"q": {query}}.Encode())
+}
+
+// printSnippets fetches a page of snippets and prints one per line, with
+// the slug first so that the output is easy to use in scripts
+func (c *cli) printSnippets(path string) error {
+    cfg, err := c.loadConfig()
+    if err != nil {
+        return err
+    }
+
+    var list snippetList
+    err = newClient(cfg).do(http.MethodGet, path, nil, &list)
+    if err != nil {
+        return err
+    }
+
+    tw := tabwriter.NewWriter(c.stdout, 0, 8, 2, ' ', 0)
+    for _, s := range list.Snippets {
+        fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", s.Slug, s.Title, s.Author, s.Created.Local().Format("2006-01-02"))
+    }
+
+    return tw.Flush()
+}
+
+// snippetSlug returns the slug of a snippet given either the slug itself
+// or a URL of the snippet, such as the one printed by create
+func snippetSlug(s string) string {
+    if u, err := url.Parse(s); err == nil && u.Scheme != "" {
+        return path.Base(u.Path)
+    }
+
+    return s
+}
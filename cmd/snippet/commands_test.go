@@ -0,0 +1,236 @@
+package main
+
+import (
+    "bytes"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+// the token the fake server accepts
+const testToken = "sbx_writewritewritewritewritewritewritewrite"
+
+// newTestAPI starts a server that answers like the snippetbox API for the
+// snippet pond234567, and records the body of the last request
+func newTestAPI(t *testing.T, lastBody *string) *httptest.Server {
+    content := "An old silent pond...\n"
+    pond := snippet{Slug: "pond234567", Title: "An old silent pond", Content: &content, Author: "Alice Jones", URL: "/snippet/view/pond234567"}
+
+    mux := http.NewServeMux()
+
+    mux.HandleFunc("/api/v1/snippets", func(w http.ResponseWriter, r *http.Request) {
+        if auth := r.Header.Get("Authorization"); auth != "" && auth != "Bearer "+testToken {
+            w.WriteHeader(http.StatusUnauthorized)
+            io.WriteString(w, `{"error":"Unauthorized"}`)
+            return
+        }
+
+        switch r.Method {
+        case http.MethodGet:
+            list := snippetList{Snippets: []snippet{}}
+            if q := r.URL.Query().Get("q"); q == "" || strings.Contains(content, q) {
+                list.Snippets = append(list.Snippets, pond)
+            }
+            json.NewEncoder(w).Encode(list)
+        case http.MethodPost:
+            b, _ := io.ReadAll(r.Body)
+            *lastBody = string(b)
+
+            if r.Header.Get("Authorization") == "" {
+                w.WriteHeader(http.StatusUnauthorized)
+                io.WriteString(w, `{"error":"Unauthorized"}`)
+                return
+            }
+            if strings.Contains(*lastBody, `"title":""`) {
+                w.WriteHeader(http.StatusUnprocessableEntity)
+                io.WriteString(w, `{"error":"Unprocessable Entity","errors":{"title":"This field cannot be blank"}}`)
+                return
+            }
+
+            w.WriteHeader(http.StatusCreated)
+            io.WriteString(w, `{"slug":"fuji234567","url":"/snippet/view/fuji234567"}`)
+        }
+    })
+
+    mux.HandleFunc("/api/v1/snippets/", func(w http.ResponseWriter, r *http.Request) {
+        if strings.TrimPrefix(r.URL.Path, "/api/v1/snippets/") != pond.Slug {
+            w.WriteHeader(http.StatusNotFound)
+            io.WriteString(w, `{"error":"Not Found"}`)
+            return
+        }
+        json.NewEncoder(w).Encode(pond)
+    })
+
+    ts := httptest.NewServer(mux)
+    t.Cleanup(ts.Close)
+
+    return ts
+}
+
+// newTestCLI returns a CLI with a config directory of its own, using the
+// given server and token, and the buffers it writes to
+func newTestCLI(t *testing.T, server, token, stdin string) (*cli, *bytes.Buffer, *bytes.Buffer) {
+    var stdout, stderr bytes.Buffer
+
+    env := map[string]string{envServer: server, envToken: token}
+    c := &cli{
+        stdin:     strings.NewReader(stdin),
+        stdout:    &stdout,
+        stderr:    &stderr,
+        getenv:    func(key string) string { return env[key] },
+        configDir: t.TempDir(),
+    }
+
+    return c, &stdout, &stderr
+}
+
+func TestCreate(t *testing.T) {
+    var body string
+    ts := newTestAPI(t, &body)
+
+    c, stdout, _ := newTestCLI(t, ts.URL, testToken, "package main\n")
+
+    err := c.run([]string{"create", "-t", "Quick fix", "-e", "7d"})
+    assert.NilError(t, err)
+    assert.Equal(t, stdout.String(), ts.URL+"/snippet/view/fuji234567\n")
+    assert.Equal(t, body, `{"title":"Quick fix","content":"package main\n","expires":"7d"}`)
+
+    c, _, _ = newTestCLI(t, ts.URL, testToken, "")
+
+    err = c.run([]string{"create", "-t", "", "-v", "private"})
+    assert.Equal(t, err.Error(), "Unprocessable Entity\n  title: This field cannot be blank")
+
+    c, _, _ = newTestCLI(t, ts.URL, "", "package main\n")
+
+    err = c.run([]string{"create", "-t", "Quick fix"})
+    assert.Equal(t, isStatus(err, http.StatusUnauthorized), true)
+
+    c, _, _ = newTestCLI(t, ts.URL, testToken, "")
+
+    err = c.run([]string{"create", "one.go", "two.go"})
+    assert.Equal(t, err, errUsage)
+}
+
+func TestGet(t *testing.T) {
+    ts := newTestAPI(t, nil)
+
+    tests := []struct {
+        name       string
+        args       []string
+        wantStdout string
+        wantErr    string
+    }{
+        {
+            name:       "Slug",
+            args:       []string{"get", "pond234567"},
+            wantStdout: "An old silent pond...\n",
+        },
+        {
+            name:       "URL",
+            args:       []string{"get", "https://snippets.example.com/snippet/view/pond234567"},
+            wantStdout: "An old silent pond...\n",
+        },
+        {
+            name:    "Non-existent slug",
+            args:    []string{"get", "missing234"},
+            wantErr: "snippet missing234 not found",
+        },
+        {
+            name:    "No slug",
+            args:    []string{"get"},
+            wantErr: "usage",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c, stdout, _ := newTestCLI(t, ts.URL, "", "")
+
+            err := c.run(tt.args)
+            if tt.wantErr != "" {
+                assert.Equal(t, err.Error(), tt.wantErr)
+                return
+            }
+
+            assert.NilError(t, err)
+            assert.Equal(t, stdout.String(), tt.wantStdout)
+        })
+    }
+}
+
+func TestListAndSearch(t *testing.T) {
+    ts := newTestAPI(t, nil)
+
+    c, stdout, _ := newTestCLI(t, ts.URL, "", "")
+    assert.NilError(t, c.run([]string{"list"}))
+    assert.StringContains(t, stdout.String(), "pond234567  An old silent pond  Alice Jones")
+
+    c, stdout, _ = newTestCLI(t, ts.URL, "", "")
+    assert.NilError(t, c.run([]string{"search", "silent", "pond"}))
+    assert.StringContains(t, stdout.String(), "pond234567")
+
+    c, stdout, _ = newTestCLI(t, ts.URL, "", "")
+    assert.NilError(t, c.run([]string{"search", "heron"}))
+    assert.Equal(t, stdout.String(), "")
+
+    c, _, _ = newTestCLI(t, ts.URL, "", "")
+    assert.Equal(t, c.run([]string{"search"}), errUsage)
+}
+
+func TestLogin(t *testing.T) {
+    ts := newTestAPI(t, nil)
+
+    c, _, stderr := newTestCLI(t, "", "", testToken+"\n")
+
+    err := c.run([]string{"login", "-server", ts.URL + "/"})
+    assert.NilError(t, err)
+    assert.StringContains(t, stderr.String(), "Logged in to "+ts.URL)
+
+    cfg, err := c.loadConfig()
+    assert.NilError(t, err)
+    assert.Equal(t, cfg.Server, ts.URL)
+    assert.Equal(t, cfg.Token, testToken)
+
+    // a token the server refuses is not saved
+    c, _, _ = newTestCLI(t, "", "", "sbx_wrong\n")
+
+    err = c.run([]string{"login", "-server", ts.URL})
+    assert.Equal(t, isStatus(err, http.StatusUnauthorized), true)
+
+    cfg, err = c.loadConfig()
+    assert.NilError(t, err)
+    assert.Equal(t, cfg.Token, "")
+
+    c, _, _ = newTestCLI(t, "", "", "\n")
+    assert.Equal(t, c.run([]string{"login", "-server", ts.URL}).Error(), "no API token given")
+}
+
+func TestLoginIgnoresEnvironment(t *testing.T) {
+    ts := newTestAPI(t, nil)
+
+    c, _, _ := newTestCLI(t, "https://override.example.com", "", testToken+"\n")
+    assert.NilError(t, c.run([]string{"login", "-server", ts.URL}))
+
+    // without -server, login goes back to the saved server rather than
+    // the one in the environment
+    c.stdin = strings.NewReader(testToken + "\n")
+    assert.NilError(t, c.run([]string{"login"}))
+
+    cfg, err := c.readConfig()
+    assert.NilError(t, err)
+    assert.Equal(t, cfg.Server, ts.URL)
+}
+
+func TestUnknownCommand(t *testing.T) {
+    c, _, stderr := newTestCLI(t, "", "", "")
+
+    assert.Equal(t, c.run([]string{"paste"}), errUsage)
+    assert.StringContains(t, stderr.String(), `unknown command "paste"`)
+
+    assert.Equal(t, c.run(nil), errUsage)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+    "errors"
+    "io/fs"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "github.com/BurntSushi/toml"
+)
+
+// the server used until another is given to snippet login, which is where
+// the snippetbox server listens by default
+const defaultServer = "https://localhost:4000"
+
+// the environment variables that override the saved settings, so that
+// scripts and CI jobs can run without logging in
+const (
+    envServer = "SNIPPETBOX_SERVER"
+    envToken  = "SNIPPETBOX_TOKEN"
+)
+
+// config holds the settings saved by snippet login
+type config struct {
+    Server   string `toml:"server"`
+    Token    string `toml:"token"`
+    // Insecure turns off checking of the server's TLS certificate, for
+    // servers using the self-signed certificate from development
+    Insecure bool   `toml:"insecure"`
+}
+
+// configPath returns the path of the config file
+func (c *cli) configPath() (string, error) {
+    if c.configDir == "" {
+        return "", errors.New("cannot find the user config directory")
+    }
+
+    return filepath.Join(c.configDir, "snippetbox", "snippet.toml"), nil
+}
+
+// loadConfig reads the saved settings, then applies the environment. A
+// missing config file is not an error, since the environment may be all
+// that is needed
+func (c *cli) loadConfig() (config, error) {
+    cfg, err := c.readConfig()
+    if err != nil {
+        return config{}, err
+    }
+
+    if server := c.getenv(envServer); server != "" {
+        cfg.Server = server
+    }
+    if token := c.getenv(envToken); token != "" {
+        cfg.Token = token
+    }
+
+    cfg.Server = strings.TrimRight(cfg.Server, "/")
+
+    return cfg, nil
+}
+
+// readConfig reads the saved settings alone, without the environment, for
+// snippet login to update. A missing config file gives the defaults
+func (c *cli) readConfig() (config, error) {
+    cfg := config{Server: defaultServer}
+
+    path, err := c.configPath()
+    if err == nil {
+        _, err = toml.DecodeFile(path, &cfg)
+    }
+    if err != nil && !errors.Is(err, fs.ErrNotExist) {
+        return config{}, err
+    }
+
+    return cfg, nil
+}
+
+// saveConfig writes the settings to the config file. The file holds an
+// API token, so only the user may read it
+func (c *cli) saveConfig(cfg config) error {
+    path, err := c.configPath()
+    if err != nil {
+        return err
+    }
+
+    err = os.MkdirAll(filepath.Dir(path), 0700)
+    if err != nil {
+        return err
+    }
+
+    f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+    if err != nil {
+        return err
+    }
+
+    err = toml.NewEncoder(f).Encode(cfg)
+    if err != nil {
+        f.Close()
+        return err
+    }
+
+    return f.Close()
+}
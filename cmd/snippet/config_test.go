@@ -0,0 +1,41 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestConfig(t *testing.T) {
+    env := map[string]string{}
+    c := &cli{
+        getenv:    func(key string) string { return env[key] },
+        configDir: t.TempDir(),
+    }
+
+    cfg, err := c.loadConfig()
+    assert.NilError(t, err)
+    assert.Equal(t, cfg, config{Server: defaultServer})
+
+    err = c.saveConfig(config{Server: "https://snippets.example.com", Token: "sbx_token", Insecure: true})
+    assert.NilError(t, err)
+
+    info, err := os.Stat(filepath.Join(c.configDir, "snippetbox", "snippet.toml"))
+    assert.NilError(t, err)
+    assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+
+    cfg, err = c.loadConfig()
+    assert.NilError(t, err)
+    assert.Equal(t, cfg, config{Server: "https://snippets.example.com", Token: "sbx_token", Insecure: true})
+
+    // the environment wins over the file
+    env[envServer] = "https://localhost:4000/"
+    env[envToken] = "sbx_other"
+
+    cfg, err = c.loadConfig()
+    assert.NilError(t, err)
+    assert.Equal(t, cfg.Server, "https://localhost:4000")
+    assert.Equal(t, cfg.Token, "sbx_other")
+}
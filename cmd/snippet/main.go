@@ -0,0 +1,124 @@
+// Command snippet is a command line client for snippetbox. It talks to the
+// JSON API of a snippetbox server with a personal API token, which
+// "snippet login" saves in the user's config directory:
+//
+//	snippet login -server https://snippets.example.com
+//	snippet create -t "Quick fix" -e 7d < fix.go
+//	snippet get pond234567 > pond.txt
+//	snippet list
+//	snippet search frog
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+)
+
+const usage = `Usage: snippet <command> [flags] [arguments]
+
+Commands:
+  login   save the server and an API token to use
+  create  create a snippet from a file or standard input
+  get     print the content of a snippet
+  list    list the latest public snippets
+  search  search public snippets
+
+Run "snippet <command> -h" for the flags of a command.
+`
+
+// errUsage reports a mistake in the command line, after which the usage
+// has already been printed
+var errUsage = errors.New("usage")
+
+// cli holds what the commands read from and write to, so that tests can
+// supply their own
+type cli struct {
+    stdin     io.Reader
+    stdout    io.Writer
+    stderr    io.Writer
+    getenv    func(string) string
+    // configDir is the directory holding the config file
+    configDir string
+}
+
+func main() {
+    c := &cli{
+        stdin:  os.Stdin,
+        stdout: os.Stdout,
+        stderr: os.Stderr,
+        getenv: os.Getenv,
+    }
+
+    dir, err := os.UserConfigDir()
+    if err == nil {
+        c.configDir = dir
+    }
+
+    err = c.run(os.Args[1:])
+    switch {
+    case err == nil:
+    case errors.Is(err, errUsage):
+        os.Exit(2)
+    case errors.Is(err, flag.ErrHelp):
+        return
+    default:
+        fmt.Fprintf(os.Stderr, "snippet: %s\n", err)
+        os.Exit(1)
+    }
+}
+
+// run runs the command named by the first argument
+func (c *cli) run(args []string) error {
+    if len(args) == 0 {
+        fmt.Fprint(c.stderr, usage)
+        return errUsage
+    }
+
+    commands := map[string]func([]string) error{
+        "login":  c.login,
+        "create": c.create,
+        "get":    c.get,
+        "list":   c.list,
+        "search": c.search,
+    }
+
+    command, ok := commands[args[0]]
+    if !ok {
+        if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+            fmt.Fprint(c.stdout, usage)
+            return nil
+        }
+
+        fmt.Fprintf(c.stderr, "snippet: unknown command %q\n\n%s", args[0], usage)
+        return errUsage
+    }
+
+    return command(args[1:])
+}
+
+// flagSet returns a FlagSet for a command that writes its messages to the
+// CLI's stderr
+func (c *cli) flagSet(name, synopsis string) *flag.FlagSet {
+    fs := flag.NewFlagSet(name, flag.ContinueOnError)
+    fs.SetOutput(c.stderr)
+    fs.Usage = func() {
+        fmt.Fprintf(c.stderr, "Usage: snippet %s %s\n", name, synopsis)
+        fs.PrintDefaults()
+    }
+
+    return fs
+}
+
+// parse parses the flags of a command, turning a mistake in them into
+// errUsage since the FlagSet has already explained it
+func parse(fs *flag.FlagSet, args []string) error {
+    err := fs.Parse(args)
+    if err != nil && !errors.Is(err, flag.ErrHelp) {
+        return errUsage
+    }
+
+    return err
+}
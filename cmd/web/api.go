@@ -0,0 +1,453 @@
+package main
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/j-clemons/snippetbox/internal/models"
+
+    "github.com/julienschmidt/httprouter"
+)
+
+// the prefix of every route of the JSON API
+const apiPrefix = "/api/v1"
+
+// the largest request body the API will read, which leaves plenty of room
+// for the content of a snippet
+const apiMaxBodyBytes = 1 << 20
+
+// apiSnippet is how a snippet is represented in the JSON API. Content is
+// left out of snippets locked with a passphrase unless they belong to the
+// user making the request
+type apiSnippet struct {
+    Slug             string     `json:"slug"`
+    Title            string     `json:"title"`
+    Content          *string    `json:"content,omitempty"`
+    Language         string     `json:"language"`
+    Visibility       string     `json:"visibility"`
+    Created          time.Time  `json:"created"`
+    // Expires is null for snippets that never expire
+    Expires          *time.Time `json:"expires"`
+    BurnAfterReading bool       `json:"burn_after_reading"`
+    Locked           bool       `json:"locked"`
+    Author           string     `json:"author"`
+    URL              string     `json:"url"`
+}
+
+// apiError is the envelope every error response of the API is sent in.
+// Errors holds the problems with each field of an invalid request, keyed
+// by field name just as on the HTML forms
+type apiError struct {
+    Error  string            `json:"error"`
+    Errors map[string]string `json:"errors,omitempty"`
+}
+
+// newAPISnippet converts a snippet to its JSON representation for the
+// user with the given ID, who is 0 for anonymous requests
+func newAPISnippet(snippet models.Snippet, userID int) apiSnippet {
+    s := apiSnippet{
+        Slug:             snippet.Slug,
+        Title:            snippet.Title,
+        Language:         snippet.Language,
+        Visibility:       snippet.Visibility,
+        Created:          snippet.Created.UTC(),
+        BurnAfterReading: snippet.BurnAfterReading,
+        Locked:           snippet.Locked,
+        Author:           snippet.UserName,
+        URL:              fmt.Sprintf("/snippet/view/%s", snippet.Slug),
+    }
+
+    if !snippet.Expires.IsZero() {
+        expires := snippet.Expires.UTC()
+        s.Expires = &expires
+    }
+
+    if !snippet.Locked || snippet.UserID == userID {
+        content := snippet.Content
+        s.Content = &content
+    }
+
+    return s
+}
+
+// apiSnippetList lists every public snippet, newest first, a page at a
+// time. Like the archive it is paged by the id of the last snippet on the
+// page, and next holds the URL of the following page when there is one.
+// With a q parameter it lists the results of searching for q instead,
+// which are paged by number like the search page
+func (app *application) apiSnippetList(w http.ResponseWriter, r *http.Request) {
+    var snippets []models.Snippet
+    var next string
+
+    if query := strings.TrimSpace(r.URL.Query().Get("q")); query != "" {
+        page, err := queryInt(r, "page", 1)
+        if err != nil || page < 1 {
+            app.apiClientError(w, http.StatusBadRequest)
+            return
+        }
+
+        snippets, err = app.snippets.Search(r.Context(), query, page, searchPerPage)
+        if err != nil {
+            app.apiServerError(w, r, err)
+            return
+        }
+
+        // a full page suggests there may be more results after it
+        if len(snippets) == searchPerPage {
+            values := url.Values{"q": {query}, "page": {strconv.Itoa(page + 1)}}
+            next = fmt.Sprintf("%s/snippets?%s", apiPrefix, values.Encode())
+        }
+    } else {
+        before, err := queryInt(r, "before", 0)
+        if err != nil || before < 0 {
+            app.apiClientError(w, http.StatusBadRequest)
+            return
+        }
+
+        snippets, err = app.snippets.Archive(r.Context(), before, 0, app.config.PageSize+1)
+        if err != nil {
+            app.apiServerError(w, r, err)
+            return
+        }
+
+        if len(snippets) > app.config.PageSize {
+            snippets = snippets[:app.config.PageSize]
+            next = fmt.Sprintf("%s/snippets?before=%d", apiPrefix, snippets[len(snippets)-1].ID)
+        }
+    }
+
+    userID := app.apiUserID(r)
+
+    data := struct {
+        Snippets []apiSnippet `json:"snippets"`
+        Next     string       `json:"next,omitempty"`
+    }{
+        Snippets: []apiSnippet{},
+        Next:     next,
+    }
+
+    for _, snippet := range snippets {
+        data.Snippets = append(data.Snippets, newAPISnippet(snippet, userID))
+    }
+
+    app.writeJSON(w, r, http.StatusOK, data)
+}
+
+// apiSnippetCreate creates a snippet owned by the authenticated user from
+// a JSON object with the same fields as the create form. Fields that are
+// left out take the defaults the form starts with
+func (app *application) apiSnippetCreate(w http.ResponseWriter, r *http.Request) {
+    form := snippetCreateForm{
+        Visibility: models.VisibilityPublic,
+        Expires:    "1y",
+    }
+
+    if !app.readJSON(w, r, &form) {
+        return
+    }
+
+    form.check(false)
+
+    if !form.Valid() {
+        app.apiValidationError(w, form.FieldErrors)
+        return
+    }
+
+    slug, err := app.snippets.Insert(r.Context(), form.Title, form.Content, form.language(), form.Visibility, form.expiresAt, form.Burn, form.Password, app.apiUserID(r))
+    if err != nil {
+        app.apiServerError(w, r, err)
+        return
+    }
+
+    snippet, err := app.snippets.GetBySlug(r.Context(), slug)
+    if err != nil {
+        app.apiServerError(w, r, err)
+        return
+    }
+
+    w.Header().Set("Location", fmt.Sprintf("%s/snippets/%s", apiPrefix, slug))
+    app.writeJSON(w, r, http.StatusCreated, newAPISnippet(snippet, app.apiUserID(r)))
+}
+
+// apiSnippetView sends a single snippet. Burn after reading snippets are
+// only ever revealed through the web interface, so they are not found here
+func (app *application) apiSnippetView(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.apiLookupSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    if snippet.BurnAfterReading {
+        app.apiNotFound(w)
+        return
+    }
+
+    app.writeJSON(w, r, http.StatusOK, newAPISnippet(snippet, app.apiUserID(r)))
+}
+
+// apiSnippetUpdate changes a snippet owned by the authenticated user.
+// Only the fields present in the request are changed, and the expiry is
+// kept unless a new one is given. Whether the snippet burns after reading
+// and its passphrase are fixed when it is created
+func (app *application) apiSnippetUpdate(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.apiOwnedSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    form := snippetCreateForm{
+        Title:      snippet.Title,
+        Content:    snippet.Content,
+        Language:   snippet.Language,
+        Visibility: snippet.Visibility,
+        Expires:    expiresKeep,
+        expiresAt:  snippet.Expires,
+    }
+
+    if !app.readJSON(w, r, &form) {
+        return
+    }
+
+    form.check(true)
+    form.CheckField(!form.Burn, "burn", "This field cannot be changed")
+    form.CheckField(form.Password == "", "password", "This field cannot be changed")
+
+    if !form.Valid() {
+        app.apiValidationError(w, form.FieldErrors)
+        return
+    }
+
+    err := app.snippets.Update(r.Context(), snippet.ID, form.Title, form.Content, form.language(), form.Visibility, form.expiresAt)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.apiNotFound(w)
+        } else {
+            app.apiServerError(w, r, err)
+        }
+        return
+    }
+
+    snippet, err = app.snippets.Get(r.Context(), snippet.ID)
+    if err != nil {
+        app.apiServerError(w, r, err)
+        return
+    }
+
+    app.writeJSON(w, r, http.StatusOK, newAPISnippet(snippet, app.apiUserID(r)))
+}
+
+// apiSnippetDelete deletes a snippet owned by the authenticated user
+func (app *application) apiSnippetDelete(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.apiOwnedSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    err := app.snippets.Delete(r.Context(), snippet.ID)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.apiNotFound(w)
+        } else {
+            app.apiServerError(w, r, err)
+        }
+        return
+    }
+
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// apiLookupSnippet works like lookupSnippet for the API. Snippets are only
+// named by their slug here, and the user is the owner of the API token
+// rather than the one logged in to the session
+func (app *application) apiLookupSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+    params := httprouter.ParamsFromContext(r.Context())
+    slug := params.ByName("id")
+
+    if !models.SlugRX.MatchString(slug) {
+        app.apiNotFound(w)
+        return models.Snippet{}, false
+    }
+
+    snippet, err := app.snippets.GetBySlug(r.Context(), slug)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.apiNotFound(w)
+        } else {
+            app.apiServerError(w, r, err)
+        }
+        return models.Snippet{}, false
+    }
+
+    if snippet.Visibility == models.VisibilityPrivate && snippet.UserID != app.apiUserID(r) {
+        app.apiNotFound(w)
+        return models.Snippet{}, false
+    }
+
+    return snippet, true
+}
+
+// apiOwnedSnippet works like apiLookupSnippet but also checks that the
+// snippet belongs to the authenticated user
+func (app *application) apiOwnedSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+    snippet, ok := app.apiLookupSnippet(w, r)
+    if !ok {
+        return models.Snippet{}, false
+    }
+
+    if snippet.UserID != app.apiUserID(r) {
+        app.apiClientError(w, http.StatusForbidden)
+        return models.Snippet{}, false
+    }
+
+    return snippet, true
+}
+
+// apiUserID returns the ID of the user whose token authenticated the
+// request, or 0 if the request is anonymous
+func (app *application) apiUserID(r *http.Request) int {
+    token, ok := app.apiToken(r)
+    if !ok {
+        return 0
+    }
+
+    return token.UserID
+}
+
+// apiToken returns the API token that authenticated the request, if any
+func (app *application) apiToken(r *http.Request) (models.Token, bool) {
+    token, ok := r.Context().Value(apiTokenContextKey).(models.Token)
+    return token, ok
+}
+
+// readJSON decodes the JSON object in the request body into dst. Fields
+// that dst does not have are refused, as is anything after the object. If
+// the body cannot be decoded a 400 Bad Request has already been sent and
+// ok is false
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+    r.Body = http.MaxBytesReader(w, r.Body, apiMaxBodyBytes)
+
+    dec := json.NewDecoder(r.Body)
+    dec.DisallowUnknownFields()
+
+    err := dec.Decode(dst)
+    if err == nil {
+        err = dec.Decode(&struct{}{})
+        if errors.Is(err, io.EOF) {
+            return true
+        }
+        err = errors.New("body must only contain a single JSON object")
+    }
+
+    var maxBytesError *http.MaxBytesError
+    if errors.As(err, &maxBytesError) {
+        app.writeError(w, http.StatusRequestEntityTooLarge, apiError{Error: http.StatusText(http.StatusRequestEntityTooLarge)})
+        return false
+    }
+
+    app.writeError(w, http.StatusBadRequest, apiError{Error: describeJSONError(err)})
+    return false
+}
+
+// describeJSONError turns an error from decoding a request body into a
+// message for the client
+func describeJSONError(err error) string {
+    var syntaxError *json.SyntaxError
+    var typeError *json.UnmarshalTypeError
+
+    switch {
+    case errors.As(err, &syntaxError):
+        return fmt.Sprintf("body contains badly formed JSON at character %d", syntaxError.Offset)
+    case errors.Is(err, io.ErrUnexpectedEOF):
+        return "body contains badly formed JSON"
+    case errors.As(err, &typeError):
+        if typeError.Field != "" {
+            return fmt.Sprintf("body contains the wrong type for the field %q", typeError.Field)
+        }
+        return "body must be a JSON object"
+    case errors.Is(err, io.EOF):
+        return "body must not be empty"
+    case strings.HasPrefix(err.Error(), "json: unknown field "):
+        return "body contains the unknown field " + strings.TrimPrefix(err.Error(), "json: unknown field ")
+    }
+
+    return err.Error()
+}
+
+// writeJSON sends data encoded as JSON with the given status code
+func (app *application) writeJSON(w http.ResponseWriter, r *http.Request, status int, data any) {
+    js, err := json.Marshal(data)
+    if err != nil {
+        app.apiServerError(w, r, err)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    w.Write(append(js, '\n'))
+}
+
+// writeError sends an error envelope. Encoding it cannot fail, so unlike
+// writeJSON it needs no request to report errors against
+func (app *application) writeError(w http.ResponseWriter, status int, e apiError) {
+    js, _ := json.Marshal(e)
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    w.Write(append(js, '\n'))
+}
+
+// apiServerError is the API's version of serverError
+func (app *application) apiServerError(w http.ResponseWriter, r *http.Request, err error) {
+    if models.IsTimeout(err) {
+        app.logger.Warn(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+
+        w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+        app.apiClientError(w, http.StatusServiceUnavailable)
+        return
+    }
+
+    app.logger.Error(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+    app.apiClientError(w, http.StatusInternalServerError)
+}
+
+// apiClientError sends an error envelope with the description of status
+func (app *application) apiClientError(w http.ResponseWriter, status int) {
+    app.writeError(w, status, apiError{Error: http.StatusText(status)})
+}
+
+func (app *application) apiNotFound(w http.ResponseWriter) {
+    app.apiClientError(w, http.StatusNotFound)
+}
+
+// apiUnauthorized asks the client to authenticate with an API token. The
+// error, if not blank, says what was wrong with the token it sent
+func (app *application) apiUnauthorized(w http.ResponseWriter, tokenError string) {
+    challenge := `Bearer realm="snippetbox"`
+    if tokenError != "" {
+        challenge += fmt.Sprintf(`, error=%q`, tokenError)
+    }
+
+    w.Header().Set("WWW-Authenticate", challenge)
+    app.apiClientError(w, http.StatusUnauthorized)
+}
+
+// apiValidationError sends the field errors of an invalid request
+func (app *application) apiValidationError(w http.ResponseWriter, fieldErrors map[string]string) {
+    app.writeError(w, http.StatusUnprocessableEntity, apiError{
+        Error:  http.StatusText(http.StatusUnprocessableEntity),
+        Errors: fieldErrors,
+    })
+}
+
+// isAPIRequest reports whether a request is for the JSON API, so that the
+// router's own error responses can be sent in the same envelope
+func isAPIRequest(r *http.Request) bool {
+    return r.URL.Path == apiPrefix || strings.HasPrefix(r.URL.Path, apiPrefix+"/")
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "log/slog"
+    "os"
+    "sync/atomic"
+    "time"
+)
+
+// certReloader serves the TLS certificate to the server through
+// tls.Config.GetCertificate, so that it can be replaced while the server
+// is running. The certificate is swapped atomically, and only once the new
+// certificate and key have been loaded and checked to belong together
+type certReloader struct {
+    certFile string
+    keyFile  string
+    logger   *slog.Logger
+    cert     atomic.Pointer[tls.Certificate]
+    // the modification times of the files when they were last loaded,
+    // which are only used by the watch() goroutine
+    certMod  time.Time
+    keyMod   time.Time
+}
+
+// newCertReloader loads the certificate and key, failing if they cannot
+// be loaded since the server is no use without them
+func newCertReloader(certFile, keyFile string, logger *slog.Logger) (*certReloader, error) {
+    c := &certReloader{
+        certFile: certFile,
+        keyFile:  keyFile,
+        logger:   logger,
+    }
+
+    err := c.reload()
+    if err != nil {
+        return nil, err
+    }
+
+    return c, nil
+}
+
+// reload loads the certificate and key from disk and starts serving them.
+// tls.LoadX509KeyPair() refuses a key that does not match the certificate,
+// in which case the current certificate is kept. The files are not tried
+// again until one of them changes, such as when the other half of a new
+// pair is written
+func (c *certReloader) reload() error {
+    c.certMod, c.keyMod = c.modTimes()
+
+    cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+    if err != nil {
+        return err
+    }
+
+    leaf, err := x509.ParseCertificate(cert.Certificate[0])
+    if err != nil {
+        return err
+    }
+    cert.Leaf = leaf
+
+    c.cert.Store(&cert)
+
+    c.logger.Info("loaded TLS certificate", "subject", leaf.Subject.String(), "expires", leaf.NotAfter.UTC())
+
+    return nil
+}
+
+// GetCertificate returns the current certificate for every handshake
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+    return c.cert.Load(), nil
+}
+
+// modTimes returns the modification times of the certificate and key
+// files, or the zero time for a file that cannot be read
+func (c *certReloader) modTimes() (time.Time, time.Time) {
+    var certMod, keyMod time.Time
+
+    if fi, err := os.Stat(c.certFile); err == nil {
+        certMod = fi.ModTime()
+    }
+    if fi, err := os.Stat(c.keyFile); err == nil {
+        keyMod = fi.ModTime()
+    }
+
+    return certMod, keyMod
+}
+
+// watch reloads the certificate whenever a signal arrives on hup, which
+// main() relays SIGHUP to, and unless interval is zero when either file
+// changes, until ctx is cancelled
+func (c *certReloader) watch(ctx context.Context, hup <-chan os.Signal, interval time.Duration) {
+    // a nil channel never receives, which turns polling off
+    var tick <-chan time.Time
+    if interval > 0 {
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
+        tick = ticker.C
+    }
+
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case <-hup:
+            c.logger.Info("reloading TLS certificate", "reason", "SIGHUP")
+        case <-tick:
+            certMod, keyMod := c.modTimes()
+            if certMod.Equal(c.certMod) && keyMod.Equal(c.keyMod) {
+                continue
+            }
+            c.logger.Info("reloading TLS certificate", "reason", "files changed")
+        }
+
+        err := c.reload()
+        if err != nil {
+            c.logger.Error("keeping current TLS certificate", "error", err.Error())
+        }
+    }
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+    "context"
+    "crypto/ecdsa"
+    "crypto/elliptic"
+    "crypto/rand"
+    "crypto/x509"
+    "crypto/x509/pkix"
+    "encoding/pem"
+    "io"
+    "log/slog"
+    "math/big"
+    "os"
+    "path/filepath"
+    "syscall"
+    "testing"
+    "time"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+// writeCert writes a new self-signed certificate for name and its key to
+// the given files
+func writeCert(t *testing.T, certFile, keyFile, name string) {
+    t.Helper()
+
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    assert.NilError(t, err)
+
+    template := &x509.Certificate{
+        SerialNumber: big.NewInt(time.Now().UnixNano()),
+        Subject:      pkix.Name{CommonName: name},
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     time.Now().Add(24 * time.Hour),
+    }
+
+    der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+    assert.NilError(t, err)
+
+    keyDER, err := x509.MarshalECPrivateKey(key)
+    assert.NilError(t, err)
+
+    assert.NilError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+    assert.NilError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+// commonName returns the name on the certificate being served
+func commonName(t *testing.T, c *certReloader) string {
+    t.Helper()
+
+    cert, err := c.GetCertificate(nil)
+    assert.NilError(t, err)
+
+    return cert.Leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+    dir := t.TempDir()
+    certFile := filepath.Join(dir, "cert.pem")
+    keyFile := filepath.Join(dir, "key.pem")
+
+    writeCert(t, certFile, keyFile, "first")
+
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    c, err := newCertReloader(certFile, keyFile, logger)
+    assert.NilError(t, err)
+    assert.Equal(t, commonName(t, c), "first")
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    go c.watch(ctx, nil, 10*time.Millisecond)
+
+    // a new pair is picked up by the watcher
+    writeCert(t, certFile, keyFile, "second")
+    future := time.Now().Add(time.Minute)
+    assert.NilError(t, os.Chtimes(certFile, future, future))
+    assert.NilError(t, os.Chtimes(keyFile, future, future))
+
+    deadline := time.Now().Add(2 * time.Second)
+    for commonName(t, c) != "second" {
+        if time.Now().After(deadline) {
+            t.Fatal("certificate was not reloaded after the files changed")
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+}
+
+func TestCertReloaderHangup(t *testing.T) {
+    dir := t.TempDir()
+    certFile := filepath.Join(dir, "cert.pem")
+    keyFile := filepath.Join(dir, "key.pem")
+
+    writeCert(t, certFile, keyFile, "first")
+
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    c, err := newCertReloader(certFile, keyFile, logger)
+    assert.NilError(t, err)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    // with polling turned off only the signal reloads the certificate
+    hup := make(chan os.Signal, 1)
+    go c.watch(ctx, hup, 0)
+
+    writeCert(t, certFile, keyFile, "second")
+    hup <- syscall.SIGHUP
+
+    deadline := time.Now().Add(2 * time.Second)
+    for commonName(t, c) != "second" {
+        if time.Now().After(deadline) {
+            t.Fatal("certificate was not reloaded after SIGHUP")
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+}
+
+func TestCertReloaderMismatch(t *testing.T) {
+    dir := t.TempDir()
+    certFile := filepath.Join(dir, "cert.pem")
+    keyFile := filepath.Join(dir, "key.pem")
+
+    writeCert(t, certFile, keyFile, "first")
+
+    logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+    c, err := newCertReloader(certFile, keyFile, logger)
+    assert.NilError(t, err)
+
+    // replace only the certificate, so it no longer matches the key
+    writeCert(t, certFile, filepath.Join(dir, "other.pem"), "second")
+
+    err = c.reload()
+    if err == nil {
+        t.Fatal("expected a mismatched key pair to be refused")
+    }
+    assert.Equal(t, commonName(t, c), "first")
+
+    _, err = newCertReloader(certFile, keyFile, logger)
+    if err == nil {
+        t.Fatal("expected a mismatched key pair to be refused at startup")
+    }
+}
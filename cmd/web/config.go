@@ -0,0 +1,275 @@
+package main
+
+import (
+    "errors"
+    "flag"
+    "fmt"
+    "net/netip"
+    "strings"
+    "time"
+
+    "github.com/BurntSushi/toml"
+    "golang.org/x/crypto/bcrypt"
+)
+
+// config holds every setting of the application. Each setting can be given
+// in a TOML file, in an environment variable and as a command line flag,
+// with later sources overriding earlier ones:
+//
+//  1. the defaults from defaultConfig()
+//  2. the TOML file named by -config or SNIPPETBOX_CONFIG, if any
+//  3. environment variables, named after the flag with a SNIPPETBOX_
+//     prefix, so -tls-cert becomes SNIPPETBOX_TLS_CERT
+//  4. command line flags
+//
+// See config.example.toml for the keys used in the file.
+type config struct {
+    Addr     string `toml:"addr"`
+    DSN      string `toml:"dsn"`
+    PageSize int    `toml:"page_size"`
+
+    // QueryTimeout is the most time a single model call may spend on the
+    // database before the request fails with 503 Service Unavailable.
+    // Zero means no limit beyond that of the request
+    QueryTimeout time.Duration `toml:"query_timeout"`
+
+    TLS struct {
+        // Enabled is false when a reverse proxy terminates TLS and the
+        // server speaks plain HTTP
+        Enabled        bool          `toml:"enabled"`
+        Cert           string        `toml:"cert"`
+        Key            string        `toml:"key"`
+        // ReloadInterval is how often to check the certificate and key
+        // files for changes. Zero means only reloading on SIGHUP
+        ReloadInterval time.Duration `toml:"reload_interval"`
+        // RedirectAddr is the address of a second, plain HTTP listener
+        // that redirects everything to HTTPS. Blank turns it off
+        RedirectAddr   string        `toml:"redirect_addr"`
+    } `toml:"tls"`
+
+    // TrustedProxies are the IP addresses and CIDR ranges of the reverse
+    // proxies whose X-Forwarded-For and X-Forwarded-Proto headers we believe
+    TrustedProxies []string `toml:"trusted_proxies"`
+
+    Server struct {
+        IdleTimeout     time.Duration `toml:"idle_timeout"`
+        ReadTimeout     time.Duration `toml:"read_timeout"`
+        WriteTimeout    time.Duration `toml:"write_timeout"`
+        ShutdownTimeout time.Duration `toml:"shutdown_timeout"`
+    } `toml:"server"`
+
+    Session struct {
+        Lifetime time.Duration `toml:"lifetime"`
+    } `toml:"session"`
+
+    BcryptCost int `toml:"bcrypt_cost"`
+
+    Reaper reaperConfig `toml:"reaper"`
+
+    // purge runs the reaper once and exits. It is only a command line
+    // flag, since it is an action rather than a setting
+    purge bool
+
+    // args are the command line arguments left after the flags, which
+    // name a subcommand such as "migrate up"
+    args []string
+}
+
+// defaultConfig returns the settings used when nothing else is given
+func defaultConfig() config {
+    var cfg config
+
+    cfg.Addr = ":4000"
+    cfg.DSN = "web:1234@/snippetbox?parseTime=true"
+    cfg.PageSize = 20
+    cfg.QueryTimeout = 3 * time.Second
+    cfg.TLS.Enabled = true
+    cfg.TLS.Cert = "./tls/cert.pem"
+    cfg.TLS.Key = "./tls/key.pem"
+    cfg.TLS.ReloadInterval = 10 * time.Second
+    cfg.Server.IdleTimeout = time.Minute
+    cfg.Server.ReadTimeout = 5 * time.Second
+    cfg.Server.WriteTimeout = 10 * time.Second
+    cfg.Server.ShutdownTimeout = 30 * time.Second
+    cfg.Session.Lifetime = 12 * time.Hour
+    cfg.BcryptCost = 12
+    cfg.Reaper.Interval = time.Hour
+    cfg.Reaper.Batch = 500
+
+    return cfg
+}
+
+// the prefix of the environment variables that override settings
+const envPrefix = "SNIPPETBOX_"
+
+// loadConfig builds the configuration from the command line arguments
+// (without the program name) and the environment, which is read with
+// getenv, and checks that the result is valid
+func loadConfig(args []string, getenv func(string) string) (config, error) {
+    cfg := defaultConfig()
+
+    fs := flag.NewFlagSet("snippetbox", flag.ContinueOnError)
+
+    configFile := fs.String("config", "", "TOML configuration file (env "+envPrefix+"CONFIG)")
+
+    fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "HTTP network address")
+    fs.StringVar(&cfg.DSN, "dsn", cfg.DSN, "Data source name: a MySQL DSN, a postgres:// URL, or sqlite:PATH for an SQLite database")
+    fs.IntVar(&cfg.PageSize, "page-size", cfg.PageSize, "Number of snippets per archive page")
+    fs.DurationVar(&cfg.QueryTimeout, "query-timeout", cfg.QueryTimeout, "Most time a database call may take (0 for no limit)")
+    fs.BoolVar(&cfg.TLS.Enabled, "tls", cfg.TLS.Enabled, "Serve HTTPS (use -tls=false behind a TLS-terminating proxy)")
+    fs.StringVar(&cfg.TLS.Cert, "tls-cert", cfg.TLS.Cert, "TLS certificate file")
+    fs.StringVar(&cfg.TLS.Key, "tls-key", cfg.TLS.Key, "TLS private key file")
+    fs.DurationVar(&cfg.TLS.ReloadInterval, "tls-reload-interval", cfg.TLS.ReloadInterval, "How often to check the TLS files for changes (0 to only reload on SIGHUP)")
+    fs.StringVar(&cfg.TLS.RedirectAddr, "redirect-addr", cfg.TLS.RedirectAddr, "HTTP network address that redirects to HTTPS, such as :80")
+    fs.Var((*stringList)(&cfg.TrustedProxies), "trusted-proxies", "Comma-separated IP addresses or CIDR ranges of trusted reverse proxies")
+    fs.DurationVar(&cfg.Server.IdleTimeout, "idle-timeout", cfg.Server.IdleTimeout, "How long to keep idle connections open")
+    fs.DurationVar(&cfg.Server.ReadTimeout, "read-timeout", cfg.Server.ReadTimeout, "How long to wait for a request to be read")
+    fs.DurationVar(&cfg.Server.WriteTimeout, "write-timeout", cfg.Server.WriteTimeout, "How long to wait for a response to be written")
+    fs.DurationVar(&cfg.Server.ShutdownTimeout, "shutdown-timeout", cfg.Server.ShutdownTimeout, "How long to wait for requests to finish on shutdown")
+    fs.DurationVar(&cfg.Session.Lifetime, "session-lifetime", cfg.Session.Lifetime, "How long a session lasts")
+    fs.IntVar(&cfg.BcryptCost, "bcrypt-cost", cfg.BcryptCost, "Cost of bcrypt password hashes")
+    fs.DurationVar(&cfg.Reaper.Interval, "reap-interval", cfg.Reaper.Interval, "How often to purge expired snippets (0 to disable)")
+    fs.IntVar(&cfg.Reaper.Batch, "reap-batch", cfg.Reaper.Batch, "Most expired snippets to purge in one transaction")
+    fs.BoolVar(&cfg.Reaper.Archive, "reap-archive", cfg.Reaper.Archive, "Move expired snippets to the archive table instead of deleting them")
+    fs.BoolVar(&cfg.purge, "purge", false, "Purge expired snippets once and exit")
+
+    err := fs.Parse(args)
+    if err != nil {
+        return config{}, err
+    }
+
+    // remember the flags given on the command line, which have to be
+    // applied again after the file and environment have been read
+    given := map[string]string{}
+    fs.Visit(func(f *flag.Flag) {
+        given[f.Name] = f.Value.String()
+    })
+
+    if *configFile == "" {
+        *configFile = getenv(envPrefix + "CONFIG")
+    }
+
+    if *configFile != "" {
+        md, err := toml.DecodeFile(*configFile, &cfg)
+        if err != nil {
+            return config{}, err
+        }
+
+        if undecoded := md.Undecoded(); len(undecoded) > 0 {
+            return config{}, fmt.Errorf("%s: unknown setting %q", *configFile, undecoded[0].String())
+        }
+    }
+
+    // every flag apart from -config and -purge can also be set in the
+    // environment
+    var envErr error
+    fs.VisitAll(func(f *flag.Flag) {
+        if envErr != nil || f.Name == "config" || f.Name == "purge" {
+            return
+        }
+
+        name := envPrefix + strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
+        if value := getenv(name); value != "" {
+            if err := f.Value.Set(value); err != nil {
+                envErr = fmt.Errorf("invalid value %q for %s: %w", value, name, err)
+            }
+        }
+    })
+    if envErr != nil {
+        return config{}, envErr
+    }
+
+    for name, value := range given {
+        fs.Set(name, value)
+    }
+
+    err = cfg.validate()
+    if err != nil {
+        return config{}, err
+    }
+
+    cfg.args = fs.Args()
+
+    return cfg, nil
+}
+
+// validate checks that the settings make sense, returning an error that
+// lists every problem found
+func (cfg config) validate() error {
+    var errs []error
+
+    check := func(ok bool, message string) {
+        if !ok {
+            errs = append(errs, errors.New(message))
+        }
+    }
+
+    check(cfg.Addr != "", "addr must not be blank")
+    check(cfg.DSN != "", "dsn must not be blank")
+    check(cfg.PageSize >= 1, "page-size must be at least 1")
+    check(cfg.QueryTimeout >= 0, "query-timeout must not be negative")
+    check(!cfg.TLS.Enabled || (cfg.TLS.Cert != "" && cfg.TLS.Key != ""), "tls-cert and tls-key must not be blank")
+    check(cfg.TLS.ReloadInterval >= 0, "tls-reload-interval must not be negative")
+    check(cfg.TLS.Enabled || cfg.TLS.RedirectAddr == "", "redirect-addr needs tls to be enabled")
+    _, err := cfg.trustedProxies()
+    check(err == nil, fmt.Sprintf("trusted-proxies: %v", err))
+    check(cfg.Server.IdleTimeout > 0, "idle-timeout must be positive")
+    check(cfg.Server.ReadTimeout > 0, "read-timeout must be positive")
+    check(cfg.Server.WriteTimeout > 0, "write-timeout must be positive")
+    check(cfg.Server.ShutdownTimeout >= 0, "shutdown-timeout must not be negative")
+    check(cfg.Session.Lifetime > 0, "session-lifetime must be positive")
+    check(cfg.BcryptCost >= bcrypt.MinCost && cfg.BcryptCost <= bcrypt.MaxCost,
+        fmt.Sprintf("bcrypt-cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost))
+    check(cfg.Reaper.Interval >= 0, "reap-interval must not be negative")
+    check(cfg.Reaper.Batch >= 1, "reap-batch must be at least 1")
+
+    return errors.Join(errs...)
+}
+
+// trustedProxies parses TrustedProxies into prefixes. A lone address is
+// treated as a prefix that matches only itself
+func (cfg config) trustedProxies() ([]netip.Prefix, error) {
+    var prefixes []netip.Prefix
+
+    for _, proxy := range cfg.TrustedProxies {
+        if strings.Contains(proxy, "/") {
+            prefix, err := netip.ParsePrefix(proxy)
+            if err != nil {
+                return nil, err
+            }
+            prefixes = append(prefixes, prefix.Masked())
+            continue
+        }
+
+        addr, err := netip.ParseAddr(proxy)
+        if err != nil {
+            return nil, err
+        }
+        prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+    }
+
+    return prefixes, nil
+}
+
+// stringList is a flag.Value for a comma-separated list of strings
+type stringList []string
+
+func (l *stringList) String() string {
+    if l == nil {
+        return ""
+    }
+
+    return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+    *l = nil
+
+    for _, s := range strings.Split(value, ",") {
+        if s = strings.TrimSpace(s); s != "" {
+            *l = append(*l, s)
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestLoadConfig(t *testing.T) {
+    file := filepath.Join(t.TempDir(), "snippetbox.toml")
+
+    err := os.WriteFile(file, []byte(`
+addr = ":5000"
+page_size = 50
+
+[tls]
+cert = "/etc/snippetbox/cert.pem"
+
+[session]
+lifetime = "1h"
+`), 0o600)
+    assert.NilError(t, err)
+
+    env := map[string]string{
+        "SNIPPETBOX_CONFIG":   file,
+        "SNIPPETBOX_ADDR":     ":6000",
+        "SNIPPETBOX_TLS_CERT": "/run/secrets/cert.pem",
+    }
+
+    cfg, err := loadConfig([]string{"-addr", ":7000", "-trusted-proxies", "10.0.0.0/8, 192.0.2.1"}, func(key string) string { return env[key] })
+    assert.NilError(t, err)
+
+    // flags beat the environment, which beats the file, which beats
+    // the defaults
+    assert.Equal(t, cfg.Addr, ":7000")
+    assert.Equal(t, cfg.TLS.Cert, "/run/secrets/cert.pem")
+    assert.Equal(t, cfg.PageSize, 50)
+    assert.Equal(t, cfg.Session.Lifetime, time.Hour)
+    assert.Equal(t, cfg.TLS.Key, "./tls/key.pem")
+    assert.Equal(t, cfg.BcryptCost, 12)
+    assert.Equal(t, fmt.Sprint(cfg.TrustedProxies), "[10.0.0.0/8 192.0.2.1]")
+}
+
+func TestLoadConfigExample(t *testing.T) {
+    cfg, err := loadConfig([]string{"-config", "../../config.example.toml"}, func(string) string { return "" })
+    assert.NilError(t, err)
+
+    // the example documents the defaults, so loading it changes nothing
+    assert.Equal(t, fmt.Sprintf("%+v", cfg), fmt.Sprintf("%+v", defaultConfig()))
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+    tests := []struct {
+        name string
+        args []string
+        env  map[string]string
+        file string
+    }{
+        {
+            name: "Invalid flag",
+            args: []string{"-page-size", "0"},
+        },
+        {
+            name: "Invalid environment variable",
+            env:  map[string]string{"SNIPPETBOX_READ_TIMEOUT": "soon"},
+        },
+        {
+            name: "Bcrypt cost out of range",
+            env:  map[string]string{"SNIPPETBOX_BCRYPT_COST": "99"},
+        },
+        {
+            name: "Negative query timeout",
+            env:  map[string]string{"SNIPPETBOX_QUERY_TIMEOUT": "-1s"},
+        },
+        {
+            name: "Invalid trusted proxy",
+            args: []string{"-trusted-proxies", "10.0.0.0/8,proxy.internal"},
+        },
+        {
+            name: "Redirect without TLS",
+            args: []string{"-tls=false", "-redirect-addr", ":80"},
+        },
+        {
+            name: "Unknown setting in file",
+            file: "adress = \":5000\"\n",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            args := tt.args
+            if tt.file != "" {
+                file := filepath.Join(t.TempDir(), "snippetbox.toml")
+                assert.NilError(t, os.WriteFile(file, []byte(tt.file), 0o600))
+                args = append(args, "-config", file)
+            }
+
+            _, err := loadConfig(args, func(key string) string { return tt.env[key] })
+            if err == nil {
+                t.Errorf("expected an error")
+            }
+        })
+    }
+}
@@ -3,3 +3,7 @@ package main
 type contextKey string
 
 const isAuthenticatedContextKey = contextKey("isAuthenticated")
+
+// apiTokenContextKey holds the models.Token that authenticated an API
+// request, which does not have a session
+const apiTokenContextKey = contextKey("apiToken")
@@ -0,0 +1,104 @@
+package main
+
+import (
+    "database/sql"
+    "strings"
+    "time"
+
+    "github.com/j-clemons/snippetbox/internal/migrations"
+    "github.com/j-clemons/snippetbox/internal/models"
+
+    "github.com/alexedwards/scs/mysqlstore"
+    "github.com/alexedwards/scs/postgresstore"
+    "github.com/alexedwards/scs/sqlite3store"
+    "github.com/alexedwards/scs/v2"
+    _ "github.com/go-sql-driver/mysql"
+    _ "github.com/lib/pq"
+    _ "github.com/mattn/go-sqlite3"
+)
+
+// the scheme that marks a DSN as the path of an SQLite database
+const sqliteScheme = "sqlite:"
+
+// the schemes of PostgreSQL connection URLs, which are passed to the
+// driver as they are
+var postgresSchemes = []string{"postgres://", "postgresql://"}
+
+// sqliteParams are the connection settings for SQLite. Foreign keys are
+// off by default, and are needed to delete revisions along with their
+// snippet. Transactions take the write lock as soon as they begin, so that
+// two of them cannot both read a row and then fail to update it, and
+// connections wait for the lock rather than failing straight away
+const sqliteParams = "_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+
+// sessionStore is a scs session store that removes expired sessions in a
+// goroutine of its own
+type sessionStore interface {
+    scs.Store
+    StopCleanup()
+}
+
+// the openDB() func wraps sql.Open() and returns a sql.DB connection pool
+// for a given DSN, along with the dialect of the database
+func openDB(dsn string) (*sql.DB, string, error) {
+    driver, dialect, dsn := parseDSN(dsn)
+
+    db, err := sql.Open(driver, dsn)
+    if err != nil {
+        return nil, "", err
+    }
+    if err = db.Ping(); err != nil {
+        db.Close()
+        return nil, "", err
+    }
+    return db, dialect, nil
+}
+
+// parseDSN works out which database a DSN is for from its scheme, and
+// returns the name of the driver, the dialect and the DSN to give the
+// driver. A postgres:// or postgresql:// URL is for PostgreSQL, a DSN
+// starting with sqlite: is the path of an SQLite database file, which is
+// created if it does not exist, and anything else is a MySQL DSN
+func parseDSN(dsn string) (string, string, string) {
+    if path, ok := strings.CutPrefix(dsn, sqliteScheme); ok {
+        return "sqlite3", migrations.SQLite, sqliteDSN(path)
+    }
+
+    for _, scheme := range postgresSchemes {
+        if strings.HasPrefix(dsn, scheme) {
+            return "postgres", migrations.Postgres, dsn
+        }
+    }
+
+    return "mysql", migrations.MySQL, dsn
+}
+
+// sqliteDSN turns the path from an sqlite: DSN into a DSN for the driver.
+// The path may be written as a URL, as in sqlite:///var/lib/snippetbox.db,
+// and may have parameters of its own, which are kept
+func sqliteDSN(path string) string {
+    path = strings.TrimPrefix(path, "//")
+
+    if strings.Contains(path, "?") {
+        return path + "&" + sqliteParams
+    }
+
+    return path + "?" + sqliteParams
+}
+
+// newStores returns the models and the session store for the dialect of db.
+// The models give up on a call to the database after queryTimeout
+func newStores(db *sql.DB, dialect string, bcryptCost int, queryTimeout time.Duration) (models.SnippetModelInterface, models.UserModelInterface, models.TokenModelInterface, sessionStore) {
+    snippets := &models.SnippetModel{DB: db, Dialect: models.Dialect(dialect), BcryptCost: bcryptCost, QueryTimeout: queryTimeout}
+    users := &models.UserModel{DB: db, Dialect: models.Dialect(dialect), BcryptCost: bcryptCost, QueryTimeout: queryTimeout}
+    tokens := &models.TokenModel{DB: db, Dialect: models.Dialect(dialect), QueryTimeout: queryTimeout}
+
+    switch dialect {
+    case migrations.SQLite:
+        return snippets, users, tokens, sqlite3store.New(db)
+    case migrations.Postgres:
+        return snippets, users, tokens, postgresstore.New(db)
+    }
+
+    return snippets, users, tokens, mysqlstore.New(db)
+}
@@ -3,20 +3,106 @@ package main
 import (
     "errors"
     "fmt"
+    "mime"
     "net/http"
+    "net/url"
     "strconv"
+    "strings"
+    "time"
 
+    "github.com/j-clemons/snippetbox/internal/diff"
+    "github.com/j-clemons/snippetbox/internal/expiry"
     "github.com/j-clemons/snippetbox/internal/models"
+    "github.com/j-clemons/snippetbox/internal/syntax"
     "github.com/j-clemons/snippetbox/internal/validator"
 
     "github.com/julienschmidt/httprouter"
 )
 
 type snippetCreateForm struct {
-    Title               string `form:"title"`
-    Content             string `form:"content"`
-    Expires             int    `form:"expires"`
-    validator.Validator `from:"-"`
+    Title               string `form:"title" json:"title"`
+    Content             string `form:"content" json:"content"`
+    Language            string `form:"language" json:"language"`
+    Visibility          string `form:"visibility" json:"visibility"`
+    Expires             string `form:"expires" json:"expires"`
+    ExpiresIn           string `form:"expires_in" json:"expires_in"`
+    ExpiresOn           string `form:"expires_on" json:"expires_on"`
+    Burn                bool   `form:"burn" json:"burn"`
+    Password            string `form:"password" json:"password"`
+    validator.Validator `form:"-" json:"-"`
+    // expiresAt is worked out from the expiry fields by check()
+    expiresAt           time.Time
+}
+
+// the choices for when a snippet expires that are not simply a duration.
+// Keep is only offered when editing, to leave the expiry as it was
+const (
+    expiresCustom = "custom"
+    expiresDate   = "date"
+    expiresKeep   = "keep"
+)
+
+// check validates the snippet form fields. It is shared by the create
+// and edit handlers so both apply exactly the same rules, except that
+// only an edit may keep the expiry the snippet already has
+func (form *snippetCreateForm) check(editing bool) {
+    // because the Validator struct is embedded in the snippetCreateForm
+    // struct CheckFiled() can be called directly on it
+    form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
+    form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
+    form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
+    // a blank language means the language should be detected from the content
+    form.CheckField(form.Language == "" || validator.PermittedValue(form.Language, syntax.Names()...), "language", "This field must be a supported language")
+    form.CheckField(validator.PermittedValue(form.Visibility, models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityPrivate), "visibility", "This field must equal public, unlisted or private")
+    form.checkExpiry(time.Now(), editing)
+    // the passphrase is optional, but when given it must not be trivial
+    form.CheckField(form.Password == "" || validator.MinChars(form.Password, 8), "password", "This field must be at least 8 characters long")
+}
+
+// checkExpiry works out when the snippet expires from the expiry fields.
+// Expires holds either one of the preset durations, "never", or says
+// whether to use the custom duration in ExpiresIn or the date in ExpiresOn.
+// When editing it may also say to keep the current expiry
+func (form *snippetCreateForm) checkExpiry(now time.Time, editing bool) {
+    var err error
+
+    switch {
+    case form.Expires == expiresKeep && editing:
+        // the edit handlers set expiresAt to the current expiry before
+        // calling check, so there is nothing to do. A new snippet has no
+        // expiry to keep, so there keep is rejected like any other word
+        return
+    case form.Expires == expiresCustom:
+        form.expiresAt, err = expiry.In(form.ExpiresIn, now)
+    case form.Expires == expiresDate:
+        form.expiresAt, err = expiry.On(form.ExpiresOn, now)
+    default:
+        form.expiresAt, err = expiry.In(form.Expires, now)
+    }
+
+    switch {
+    case errors.Is(err, expiry.ErrTooSoon):
+        form.AddFieldError("expires", "This field must be at least an hour from now")
+    case errors.Is(err, expiry.ErrTooLate):
+        form.AddFieldError("expires", "This field must be no more than 10 years from now")
+    case err != nil:
+        form.AddFieldError("expires", "This field must be a duration such as 36h, 2w, 6mo or 1y, or a date")
+    }
+}
+
+// language returns the language chosen in the form, detecting it from the
+// content if none was chosen
+func (form *snippetCreateForm) language() string {
+    if form.Language == "" {
+        return syntax.Detect(form.Content)
+    }
+
+    return form.Language
+}
+
+type snippetUnlockForm struct {
+    Password            string `form:"password"`
+    validator.Validator `form:"-"`
 }
 
 type userSignupForm struct {
@@ -26,6 +112,12 @@ type userSignupForm struct {
     validator.Validator `form:"-"`
 }
 
+type tokenCreateForm struct {
+    Name                string `form:"name"`
+    Scope               string `form:"scope"`
+    validator.Validator `form:"-"`
+}
+
 type userLoginForm struct {
     Email               string `form:"email"`
     Password            string `form:"password"`
@@ -38,7 +130,7 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
     // can remove check for r.URL.Path != "/" because httprouter 
     // matches path exactly
 
-    snippets, err := app.snippets.Latest()
+    snippets, err := app.snippets.Latest(r.Context())
     if err != nil {
         app.serverError(w, r, err)
         return
@@ -53,22 +145,115 @@ func (app *application) home(w http.ResponseWriter, r *http.Request) {
     app.render(w, r, http.StatusOK, "home.tmpl", data)
 }
 
+// snippetArchive lists every unexpired snippet, newest first, a page at a
+// time. Pages are addressed by the id of the snippet at their edge rather
+// than by an offset, so new snippets do not shift the pages around
+func (app *application) snippetArchive(w http.ResponseWriter, r *http.Request) {
+    before, err := queryInt(r, "before", 0)
+    if err != nil || before < 0 {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    after, err := queryInt(r, "after", 0)
+    if err != nil || after < 0 {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    // fetch one more snippet than we show to find out whether there is
+    // anything beyond this page
+    snippets, err := app.snippets.Archive(r.Context(), before, after, app.config.PageSize+1)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    more := len(snippets) > app.config.PageSize
+    if more {
+        // the extra snippet is the one furthest from the cursor, which
+        // is the first when paging backwards and the last otherwise
+        if after > 0 {
+            snippets = snippets[1:]
+        } else {
+            snippets = snippets[:app.config.PageSize]
+        }
+    }
+
+    data := app.newTemplateData(r)
+    data.Snippets = snippets
+    data.Page.Size = app.config.PageSize
+
+    if len(snippets) > 0 {
+        if (after > 0 && more) || before > 0 {
+            data.Page.PrevURL = fmt.Sprintf("/snippets?after=%d", snippets[0].ID)
+        }
+        if after > 0 || more {
+            data.Page.NextURL = fmt.Sprintf("/snippets?before=%d", snippets[len(snippets)-1].ID)
+        }
+    }
+
+    app.render(w, r, http.StatusOK, "archive.tmpl", data)
+}
+
 // Add a snippetView handler function
 func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
-    params := httprouter.ParamsFromContext(r.Context())
+    // lookupSnippet() extracts the slug from the URL and uses the
+    // SnippetModel's GetBySlug() method to retrieve the snippet. If the
+    // slug is invalid, there is no matching record or the snippet is
+    // private to another user then a 404 has already been sent
+    snippet, ok := app.lookupSnippet(w, r)
+    if !ok {
+        return
+    }
 
-    // extract the value of the id parameter from query string
-    // and attempt to convert to int. If not int or less than 1
-    // return 404 error
-    id, err := strconv.Atoi(params.ByName("id"))
-    if err != nil || id < 1 {
-        app.notFound(w)
+    data := app.newTemplateData(r)
+
+    // locked snippets show a form asking for the passphrase until it has
+    // been entered, and only then go on to be displayed as normal
+    if !app.isUnlocked(r, snippet) {
+        snippet.Content = ""
+        data.Snippet = snippet
+        data.Form = snippetUnlockForm{}
+        app.render(w, r, http.StatusOK, "unlock.tmpl", data)
+        return
+    }
+
+    // burn after reading snippets are only revealed by a POST from the
+    // confirmation page, so that link previews and crawlers, which only
+    // ever make GET requests, cannot destroy them before a person sees them
+    if snippet.BurnAfterReading {
+        snippet.Content = ""
+        data.Snippet = snippet
+        app.render(w, r, http.StatusOK, "burn.tmpl", data)
+        return
+    }
+
+    data.Snippet = snippet
+
+    app.render(w, r, http.StatusOK, "view.tmpl", data)
+}
+
+// snippetRevealPost shows a burn after reading snippet and destroys it. If
+// another request got to it first the snippet is already gone, so we send
+// a 404 just as if it had never existed
+func (app *application) snippetRevealPost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.lookupSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    if !snippet.BurnAfterReading {
+        http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+        return
+    }
+
+    if !app.isUnlocked(r, snippet) {
+        app.clientError(w, http.StatusForbidden)
         return
     }
-    
-    // Use the SnippetModel's Get() method to retrieve the data for a specific
-    // record based on its ID. If no matching record, then return 404
-    snippet, err := app.snippets.Get(id)
+
+    snippet, err := app.snippets.Burn(r.Context(), snippet.ID)
     if err != nil {
         if errors.Is(err, models.ErrNoRecord) {
             app.notFound(w)
@@ -80,15 +265,238 @@ func (app *application) snippetView(w http.ResponseWriter, r *http.Request) {
 
     data := app.newTemplateData(r)
     data.Snippet = snippet
+    data.Burned = true
 
     app.render(w, r, http.StatusOK, "view.tmpl", data)
 }
 
+// the number of wrong passphrases a client may try for a snippet before
+// it has to wait, and how long it has to wait for
+const (
+    unlockAttempts = 5
+    unlockWindow   = 15 * time.Minute
+)
+
+// snippetUnlockPost checks the passphrase of a locked snippet and, if it is
+// right, remembers in the session that the snippet has been unlocked. Every
+// guess is counted per client before it is checked, and the count is reset
+// by the right one, so that passphrases cannot be brute forced even by
+// sending many guesses at once
+func (app *application) snippetUnlockPost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.lookupSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    if app.isUnlocked(r, snippet) {
+        http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+        return
+    }
+
+    var form snippetUnlockForm
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    snippet.Content = ""
+    key := fmt.Sprintf("%d:%s", snippet.ID, clientIP(r))
+
+    if !app.unlockThrottle.allow(key) {
+        form.AddNonFieldError("Too many incorrect passphrases, please try again later")
+
+        data := app.newTemplateData(r)
+        data.Snippet = snippet
+        data.Form = form
+        app.render(w, r, http.StatusTooManyRequests, "unlock.tmpl", data)
+        return
+    }
+
+    err = app.snippets.Unlock(r.Context(), snippet.ID, form.Password)
+    if err != nil {
+        if errors.Is(err, models.ErrInvalidCredentials) {
+            form.AddNonFieldError("Passphrase is incorrect")
+
+            data := app.newTemplateData(r)
+            data.Snippet = snippet
+            data.Form = form
+            app.render(w, r, http.StatusUnprocessableEntity, "unlock.tmpl", data)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.unlockThrottle.reset(key)
+    app.sessionManager.Put(r.Context(), unlockedKey(snippet), true)
+
+    http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+}
+
+// snippetRaw sends just the content of a snippet as plain text, so that
+// it can be fetched with tools like curl
+func (app *application) snippetRaw(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.readableSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    w.Write([]byte(snippet.Content))
+}
+
+// snippetDownload works like snippetRaw but asks the browser to save the
+// content as a file named after the snippet
+func (app *application) snippetDownload(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.readableSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    filename := snippetFilename(snippet)
+
+    w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+    w.Write([]byte(snippet.Content))
+}
+
+// snippetHistory lists every saved revision of a snippet, newest first,
+// with a form for choosing two of them to compare
+func (app *application) snippetHistory(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.readableSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    revisions, err := app.snippets.Revisions(r.Context(), snippet.ID)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    data := app.newTemplateData(r)
+    data.Snippet = snippet
+    data.Revisions = revisions
+
+    app.render(w, r, http.StatusOK, "history.tmpl", data)
+}
+
+// snippetDiff shows a unified diff between the two revisions given by the
+// from and to query string parameters. If either is missing we compare
+// the latest revision with the one before it
+func (app *application) snippetDiff(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.readableSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    from, err := queryInt(r, "from", 0)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    to, err := queryInt(r, "to", 0)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    // the history page always asks for both revisions, so only a bare
+    // link to the diff needs the list of revisions to find the latest
+    if from == 0 || to == 0 {
+        revisions, err := app.snippets.Revisions(r.Context(), snippet.ID)
+        if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
+
+        if len(revisions) == 0 {
+            app.notFound(w)
+            return
+        }
+
+        // revisions are ordered newest first
+        latest := revisions[0].Version
+
+        if from == 0 {
+            from = max(latest-1, 1)
+        }
+        if to == 0 {
+            to = latest
+        }
+    }
+
+    var d revisionDiff
+
+    d.From, ok = app.lookupRevision(w, r, snippet.ID, from)
+    if !ok {
+        return
+    }
+
+    d.To, ok = app.lookupRevision(w, r, snippet.ID, to)
+    if !ok {
+        return
+    }
+
+    d.Hunks = diff.Unified(d.From.Title+"\n\n"+d.From.Content, d.To.Title+"\n\n"+d.To.Content, 3)
+
+    data := app.newTemplateData(r)
+    data.Snippet = snippet
+    data.Diff = d
+
+    app.render(w, r, http.StatusOK, "diff.tmpl", data)
+}
+
+// the number of results shown on each page of search results
+const searchPerPage = 10
+
+func (app *application) search(w http.ResponseWriter, r *http.Request) {
+    query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+    page, err := queryInt(r, "page", 1)
+    if err != nil || page < 1 {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    data := app.newTemplateData(r)
+    data.Search = searchData{Query: query}
+    data.Page.Size = searchPerPage
+
+    // a blank query just shows the search form
+    if query != "" {
+        snippets, err := app.snippets.Search(r.Context(), query, page, searchPerPage)
+        if err != nil {
+            app.serverError(w, r, err)
+            return
+        }
+
+        data.Snippets = snippets
+
+        values := url.Values{"q": {query}}
+        if page > 1 {
+            values.Set("page", strconv.Itoa(page-1))
+            data.Page.PrevURL = "/search?" + values.Encode()
+        }
+        // a full page suggests there may be more results after it
+        if len(snippets) == searchPerPage {
+            values.Set("page", strconv.Itoa(page+1))
+            data.Page.NextURL = "/search?" + values.Encode()
+        }
+    }
+
+    app.render(w, r, http.StatusOK, "search.tmpl", data)
+}
+
 func (app *application) snippetCreate(w http.ResponseWriter, r *http.Request) {
     data := app.newTemplateData(r)
 
     data.Form = snippetCreateForm{
-        Expires: 365,
+        Visibility: models.VisibilityPublic,
+        Expires:    "1y",
     }
 
     app.render(w, r, http.StatusOK, "create.tmpl", data)
@@ -105,14 +513,9 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
         return
     }
 
-    // because the Validator struct is embedded in the snippetCreateForm
-    // struct CheckFiled() can be called directly on it
-    form.CheckField(validator.NotBlank(form.Title), "title", "This field cannot be blank")
-    form.CheckField(validator.MaxChars(form.Title, 100), "title", "This field cannot be more than 100 characters long")
-    form.CheckField(validator.NotBlank(form.Content), "content", "This field cannot be blank")
-    form.CheckField(validator.PermittedValue(form.Expires, 1, 7, 365), "expires", "This field must equal 1, 7 or 365")
+    form.check(false)
 
-    // if there are any errors, dump them in a plain text HTTP response
+    // if there are any errors, re-display the form with the errors
     if !form.Valid() {
         data := app.newTemplateData(r)
         data.Form = form
@@ -120,8 +523,10 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
         return
     }
 
-    // pass the data to the SnippetModel.Insert() method
-    id, err := app.snippets.Insert(form.Title, form.Content, form.Expires)
+    // pass the data to the SnippetModel.Insert() method along with the
+    // ID of the logged in user, who becomes the owner of the snippet.
+    // We get back the slug that identifies the new snippet in URLs
+    slug, err := app.snippets.Insert(r.Context(), form.Title, form.Content, form.language(), form.Visibility, form.expiresAt, form.Burn, form.Password, app.authenticatedUserID(r))
     if err != nil {
         app.serverError(w, r, err)
         return
@@ -129,10 +534,94 @@ func (app *application) snippetCreatePost(w http.ResponseWriter, r *http.Request
 
     // use the Put() method to add a string value and the 
     // corresponding key to the session data
-    app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+    if form.Burn {
+        app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created! It will be destroyed after it is read once.")
+    } else {
+        app.sessionManager.Put(r.Context(), "flash", "Snippet successfully created!")
+    }
 
     // redirect the user to the relevant page for the snippet
-    http.Redirect(w, r, fmt.Sprintf("/snippet/view/%d", id), http.StatusSeeOther)
+    http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", slug), http.StatusSeeOther)
+}
+
+func (app *application) snippetEdit(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.ownedSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    data := app.newTemplateData(r)
+    data.Snippet = snippet
+    data.Form = snippetCreateForm{
+        Title:      snippet.Title,
+        Content:    snippet.Content,
+        Language:   snippet.Language,
+        Visibility: snippet.Visibility,
+        Expires:    expiresKeep,
+    }
+
+    app.render(w, r, http.StatusOK, "edit.tmpl", data)
+}
+
+func (app *application) snippetEditPost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.ownedSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    var form snippetCreateForm
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    form.expiresAt = snippet.Expires
+    form.check(true)
+
+    if !form.Valid() {
+        data := app.newTemplateData(r)
+        data.Snippet = snippet
+        data.Form = form
+        app.render(w, r, http.StatusUnprocessableEntity, "edit.tmpl", data)
+        return
+    }
+
+    err = app.snippets.Update(r.Context(), snippet.ID, form.Title, form.Content, form.language(), form.Visibility, form.expiresAt)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.notFound(w)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Snippet successfully updated!")
+
+    http.Redirect(w, r, fmt.Sprintf("/snippet/view/%s", snippet.Slug), http.StatusSeeOther)
+}
+
+func (app *application) snippetDeletePost(w http.ResponseWriter, r *http.Request) {
+    snippet, ok := app.ownedSnippet(w, r)
+    if !ok {
+        return
+    }
+
+    err := app.snippets.Delete(r.Context(), snippet.ID)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.notFound(w)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Snippet successfully deleted!")
+
+    http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
 func (app *application) userSignup(w http.ResponseWriter, r *http.Request) {
@@ -165,7 +654,7 @@ func (app *application) userSignupPost(w http.ResponseWriter, r *http.Request) {
 
     // try to create a new user record in the DB. If email already exists
     // then add an error message to the form and return
-    err = app.users.Insert(form.Name, form.Email, form.Password)
+    err = app.users.Insert(r.Context(), form.Name, form.Email, form.Password)
     if err != nil {
         if errors.Is(err, models.ErrDuplicateEmail) {
             form.AddFieldError("email", "Email address is already in use")
@@ -211,7 +700,7 @@ func (app *application) userLoginPost(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    id, err := app.users.Authenticate(form.Email, form.Password)
+    id, err := app.users.Authenticate(r.Context(), form.Email, form.Password)
     if err != nil {
         if errors.Is(err, models.ErrInvalidCredentials) {
             form.AddNonFieldError("Email or password is incorrect")
@@ -250,6 +739,85 @@ func (app *application) userLogoutPost(w http.ResponseWriter, r *http.Request) {
     http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
+// account shows the API tokens of the logged in user, along with a form
+// for creating another
+func (app *application) account(w http.ResponseWriter, r *http.Request) {
+    app.renderAccount(w, r, http.StatusOK, tokenCreateForm{Scope: models.ScopeRead}, "")
+}
+
+func (app *application) accountTokenCreatePost(w http.ResponseWriter, r *http.Request) {
+    var form tokenCreateForm
+
+    err := app.decodePostForm(r, &form)
+    if err != nil {
+        app.clientError(w, http.StatusBadRequest)
+        return
+    }
+
+    form.CheckField(validator.NotBlank(form.Name), "name", "This field cannot be blank")
+    form.CheckField(validator.MaxChars(form.Name, 100), "name", "This field cannot be more than 100 characters long")
+    form.CheckField(validator.PermittedValue(form.Scope, models.ScopeRead, models.ScopeWrite), "scope", "This field must equal read or write")
+
+    if !form.Valid() {
+        app.renderAccount(w, r, http.StatusUnprocessableEntity, form, "")
+        return
+    }
+
+    token, err := app.tokens.Insert(r.Context(), app.authenticatedUserID(r), form.Name, form.Scope)
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    // the token cannot be shown again once this page is left, so rather
+    // than redirecting, the account page is rendered straight away with
+    // the token on it
+    app.renderAccount(w, r, http.StatusOK, tokenCreateForm{Scope: models.ScopeRead}, token)
+}
+
+func (app *application) accountTokenRevokePost(w http.ResponseWriter, r *http.Request) {
+    params := httprouter.ParamsFromContext(r.Context())
+
+    id, err := strconv.Atoi(params.ByName("id"))
+    if err != nil || id < 1 {
+        app.notFound(w)
+        return
+    }
+
+    // tokens of other users are not found, just like tokens that do not
+    // exist, since the model only deletes the user's own
+    err = app.tokens.Delete(r.Context(), id, app.authenticatedUserID(r))
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.notFound(w)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    app.sessionManager.Put(r.Context(), "flash", "Token successfully revoked!")
+
+    http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+// renderAccount renders the account page with the token form and, when
+// one has just been created, the new token
+func (app *application) renderAccount(w http.ResponseWriter, r *http.Request, status int, form tokenCreateForm, newToken string) {
+    tokens, err := app.tokens.List(r.Context(), app.authenticatedUserID(r))
+    if err != nil {
+        app.serverError(w, r, err)
+        return
+    }
+
+    data := app.newTemplateData(r)
+    data.Tokens = tokens
+    data.NewToken = newToken
+    data.Form = form
+
+    app.render(w, r, status, "account.tmpl", data)
+}
+
 func ping(w http.ResponseWriter, r *http.Request) {
     w.Write([]byte("OK"))
 }
@@ -1,11 +1,16 @@
 package main
 
 import (
+    "context"
     "net/http"
     "net/url"
+    "strings"
+    "sync"
     "testing"
+    "time"
 
     "github.com/j-clemons/snippetbox/internal/assert"
+    "github.com/j-clemons/snippetbox/internal/models"
 )
 
 func TestPing(t *testing.T) {
@@ -20,6 +25,54 @@ func TestPing(t *testing.T) {
     assert.Equal(t, body, "OK")
 }
 
+func TestSnippetArchive(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name     string
+        urlPath  string
+        wantCode int
+        wantBody string
+    }{
+        {
+            name:     "First page",
+            urlPath:  "/snippets",
+            wantCode: http.StatusOK,
+            wantBody: "<a href='/snippet/view/pond234567'>An old silent pond</a>",
+        },
+        {
+            name:     "Past the end",
+            urlPath:  "/snippets?before=1",
+            wantCode: http.StatusOK,
+            wantBody: "There's nothing to see here... yet!",
+        },
+        {
+            name:     "Invalid cursor",
+            urlPath:  "/snippets?before=foo",
+            wantCode: http.StatusBadRequest,
+        },
+        {
+            name:     "Negative cursor",
+            urlPath:  "/snippets?after=-1",
+            wantCode: http.StatusBadRequest,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            code, _, body := ts.get(t, tt.urlPath)
+
+            assert.Equal(t, code, tt.wantCode)
+
+            if tt.wantBody != "" {
+                assert.StringContains(t, body, tt.wantBody)
+            }
+        })
+    }
+}
+
 func TestSnippetView(t *testing.T) {
     app := newTestApplication(t)
 
@@ -33,16 +86,27 @@ func TestSnippetView(t *testing.T) {
         wantBody string
     }{
         {
-            name:     "Valid ID",
-            urlPath:  "/snippet/view/1",
+            name:     "Valid slug",
+            urlPath:  "/snippet/view/pond234567",
             wantCode: http.StatusOK,
             wantBody: "An old silent pond...",
         },
+        {
+            name:     "Shows author",
+            urlPath:  "/snippet/view/pond234567",
+            wantCode: http.StatusOK,
+            wantBody: "by Alice Jones",
+        },
         {
             name:     "Non-existent ID",
             urlPath:  "/snippet/view/2",
             wantCode: http.StatusNotFound,
         },
+        {
+            name:     "Private slug",
+            urlPath:  "/snippet/view/autumn2345",
+            wantCode: http.StatusNotFound,
+        },
         {
             name:     "Negative ID",
             urlPath:  "/snippet/view/-1",
@@ -53,6 +117,11 @@ func TestSnippetView(t *testing.T) {
             urlPath:  "/snippet/view/1.23",
             wantCode: http.StatusNotFound,
         },
+        {
+            name:     "Non-existent slug",
+            urlPath:  "/snippet/view/mxssing234",
+            wantCode: http.StatusNotFound,
+        },
         {
             name:     "String ID",
             urlPath:  "/snippet/view/foo",
@@ -63,6 +132,347 @@ func TestSnippetView(t *testing.T) {
             urlPath:  "/snippet/view/",
             wantCode: http.StatusNotFound,
         },
+        {
+            name:     "Database timeout",
+            urlPath:  "/snippet/view/timeout234",
+            wantCode: http.StatusServiceUnavailable,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            code, _, body := ts.get(t, tt.urlPath)
+
+            assert.Equal(t, code, tt.wantCode)
+
+            if tt.wantBody != "" {
+                assert.StringContains(t, body, tt.wantBody)
+            }
+        })
+    }
+}
+
+func TestSnippetViewLegacyID(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name     string
+        urlPath  string
+        wantCode int
+        wantLoc  string
+    }{
+        {
+            name:     "Public snippet",
+            urlPath:  "/snippet/view/1",
+            wantCode: http.StatusMovedPermanently,
+            wantLoc:  "/snippet/view/pond234567",
+        },
+        {
+            name:     "Public snippet history",
+            urlPath:  "/snippet/view/1/diff?from=1&to=2",
+            wantCode: http.StatusMovedPermanently,
+            wantLoc:  "/snippet/view/pond234567/diff?from=1&to=2",
+        },
+        {
+            name:     "Public snippet raw",
+            urlPath:  "/snippet/raw/1",
+            wantCode: http.StatusMovedPermanently,
+            wantLoc:  "/snippet/raw/pond234567",
+        },
+        {
+            name:     "Private snippet",
+            urlPath:  "/snippet/view/4",
+            wantCode: http.StatusNotFound,
+        },
+        {
+            name:     "Non-existent ID",
+            urlPath:  "/snippet/view/2",
+            wantCode: http.StatusNotFound,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            code, headers, _ := ts.get(t, tt.urlPath)
+
+            assert.Equal(t, code, tt.wantCode)
+            assert.Equal(t, headers.Get("Location"), tt.wantLoc)
+        })
+    }
+}
+
+func TestSnippetViewPrivate(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    for _, urlPath := range []string{"/snippet/view/autumn2345", "/snippet/raw/autumn2345", "/snippet/download/autumn2345", "/snippet/view/autumn2345/history"} {
+        code, _, _ := ts.get(t, urlPath)
+        assert.Equal(t, code, http.StatusNotFound)
+    }
+
+    ts.login(t)
+
+    code, _, body := ts.get(t, "/snippet/view/autumn2345")
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "First autumn morning")
+
+    code, _, _ = ts.get(t, "/snippet/raw/autumn2345")
+    assert.Equal(t, code, http.StatusOK)
+}
+
+func TestSnippetBurnAfterReading(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    // viewing the snippet only shows the confirmation page
+    code, _, body := ts.get(t, "/snippet/view/burn234567")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "<form action='/snippet/view/burn234567' method='POST'>")
+    if strings.Contains(body, "Temple bells die out. The fragrant") {
+        t.Errorf("confirmation page gives away the snippet content")
+    }
+
+    // the content cannot be read any other way either
+    for _, urlPath := range []string{"/snippet/raw/burn234567", "/snippet/download/burn234567", "/snippet/view/burn234567/history"} {
+        code, _, _ := ts.get(t, urlPath)
+        assert.Equal(t, code, http.StatusNotFound)
+    }
+
+    form := url.Values{}
+    form.Add("csrf_token", extractCSRFToken(t, body))
+
+    code, _, body = ts.postForm(t, "/snippet/view/burn234567", form)
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "Temple bells die out. The fragrant blossoms remain.")
+    assert.StringContains(t, body, "This snippet has now been destroyed.")
+
+    // revealing an ordinary snippet just sends you to it
+    code, headers, _ := ts.postForm(t, "/snippet/view/pond234567", form)
+
+    assert.Equal(t, code, http.StatusSeeOther)
+    assert.Equal(t, headers.Get("Location"), "/snippet/view/pond234567")
+}
+
+func TestSnippetUnlock(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    // the snippet starts out locked
+    code, _, body := ts.get(t, "/snippet/view/gate234567")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "<form action='/snippet/unlock/gate234567' method='POST' novalidate>")
+    if strings.Contains(body, "The light of a candle is transferred") {
+        t.Errorf("unlock page gives away the snippet content")
+    }
+
+    for _, urlPath := range []string{"/snippet/raw/gate234567", "/snippet/download/gate234567"} {
+        code, _, _ := ts.get(t, urlPath)
+        assert.Equal(t, code, http.StatusForbidden)
+    }
+
+    csrfToken := extractCSRFToken(t, body)
+
+    form := url.Values{}
+    form.Add("password", "wrong passphrase")
+    form.Add("csrf_token", csrfToken)
+
+    code, _, body = ts.postForm(t, "/snippet/unlock/gate234567", form)
+
+    assert.Equal(t, code, http.StatusUnprocessableEntity)
+    assert.StringContains(t, body, "Passphrase is incorrect")
+
+    form.Set("password", "pa$$phrase")
+
+    code, headers, _ := ts.postForm(t, "/snippet/unlock/gate234567", form)
+
+    assert.Equal(t, code, http.StatusSeeOther)
+    assert.Equal(t, headers.Get("Location"), "/snippet/view/gate234567")
+
+    // once unlocked the snippet stays unlocked for the rest of the session
+    code, _, body = ts.get(t, "/snippet/view/gate234567")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "The light of a candle is transferred to another candle.")
+
+    code, _, body = ts.get(t, "/snippet/raw/gate234567")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.Equal(t, body, "The light of a candle is transferred to another candle.")
+}
+
+func TestSnippetUnlockThrottle(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    _, _, body := ts.get(t, "/snippet/view/gate234567")
+
+    form := url.Values{}
+    form.Add("password", "wrong passphrase")
+    form.Add("csrf_token", extractCSRFToken(t, body))
+
+    for i := 0; i < unlockAttempts; i++ {
+        code, _, _ := ts.postForm(t, "/snippet/unlock/gate234567", form)
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+    }
+
+    // even the right passphrase is refused once there have been too
+    // many wrong guesses
+    form.Set("password", "pa$$phrase")
+
+    code, _, body := ts.postForm(t, "/snippet/unlock/gate234567", form)
+
+    assert.Equal(t, code, http.StatusTooManyRequests)
+    assert.StringContains(t, body, "Too many incorrect passphrases")
+}
+
+// slowUnlock is a snippet model that takes a while to check passphrases,
+// as bcrypt does, so that requests to unlock a snippet overlap
+type slowUnlock struct {
+    models.SnippetModelInterface
+}
+
+func (m slowUnlock) Unlock(ctx context.Context, id int, password string) error {
+    time.Sleep(100 * time.Millisecond)
+    return m.SnippetModelInterface.Unlock(ctx, id, password)
+}
+
+func TestSnippetUnlockThrottleConcurrent(t *testing.T) {
+    app := newTestApplication(t)
+    app.snippets = slowUnlock{app.snippets}
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    _, _, body := ts.get(t, "/snippet/view/gate234567")
+
+    form := url.Values{}
+    form.Add("password", "wrong passphrase")
+    form.Add("csrf_token", extractCSRFToken(t, body))
+
+    // guesses sent all at once must not get past the limit by each being
+    // checked before any of the others has been counted
+    guesses := unlockAttempts * 4
+    codes := make(chan int, guesses)
+
+    var wg sync.WaitGroup
+
+    for i := 0; i < guesses; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+
+            rs, err := ts.Client().PostForm(ts.URL+"/snippet/unlock/gate234567", form)
+            if err != nil {
+                t.Error(err)
+                return
+            }
+            rs.Body.Close()
+
+            codes <- rs.StatusCode
+        }()
+    }
+
+    wg.Wait()
+    close(codes)
+
+    counts := map[int]int{}
+    for code := range codes {
+        counts[code]++
+    }
+
+    assert.Equal(t, counts[http.StatusUnprocessableEntity], unlockAttempts)
+    assert.Equal(t, counts[http.StatusTooManyRequests], guesses-unlockAttempts)
+}
+
+func TestSnippetRaw(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    code, headers, body := ts.get(t, "/snippet/raw/pond234567")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.Equal(t, headers.Get("Content-Type"), "text/plain; charset=utf-8")
+    assert.Equal(t, body, "An old silent pond...")
+
+    code, _, _ = ts.get(t, "/snippet/raw/2")
+    assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestSnippetDownload(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    code, headers, body := ts.get(t, "/snippet/download/pond234567")
+
+    assert.Equal(t, code, http.StatusOK)
+    assert.Equal(t, headers.Get("Content-Type"), "text/plain; charset=utf-8")
+    assert.Equal(t, headers.Get("Content-Disposition"), "attachment; filename=an-old-silent-pond.txt")
+    assert.Equal(t, body, "An old silent pond...")
+
+    code, _, _ = ts.get(t, "/snippet/download/2")
+    assert.Equal(t, code, http.StatusNotFound)
+}
+
+func TestSnippetHistory(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name     string
+        urlPath  string
+        wantCode int
+        wantBody string
+    }{
+        {
+            name:     "History",
+            urlPath:  "/snippet/view/pond234567/history",
+            wantCode: http.StatusOK,
+            wantBody: "<td>v2</td>",
+        },
+        {
+            name:     "History of non-existent snippet",
+            urlPath:  "/snippet/view/2/history",
+            wantCode: http.StatusNotFound,
+        },
+        {
+            name:     "Default diff",
+            urlPath:  "/snippet/view/pond234567/diff",
+            wantCode: http.StatusOK,
+            wantBody: "@@ -1,3 &#43;1,3 @@",
+        },
+        {
+            name:     "Explicit diff",
+            urlPath:  "/snippet/view/pond234567/diff?from=1&to=2",
+            wantCode: http.StatusOK,
+            wantBody: "<span class='delete'>-An old pond...</span>",
+        },
+        {
+            name:     "Same revision",
+            urlPath:  "/snippet/view/pond234567/diff?from=2&to=2",
+            wantCode: http.StatusOK,
+            wantBody: "These revisions are identical.",
+        },
+        {
+            name:     "Non-existent revision",
+            urlPath:  "/snippet/view/pond234567/diff?from=1&to=9",
+            wantCode: http.StatusNotFound,
+        },
+        {
+            name:     "Invalid revision",
+            urlPath:  "/snippet/view/pond234567/diff?from=foo",
+            wantCode: http.StatusBadRequest,
+        },
     }
 
     for _, tt := range tests {
@@ -78,6 +488,231 @@ func TestSnippetView(t *testing.T) {
     }
 }
 
+func TestSearch(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    tests := []struct {
+        name     string
+        urlPath  string
+        wantCode int
+        wantBody string
+    }{
+        {
+            name:     "Empty query",
+            urlPath:  "/search",
+            wantCode: http.StatusOK,
+            wantBody: "<form action='/search' method='GET' class='search'>",
+        },
+        {
+            name:     "Match",
+            urlPath:  "/search?q=silent",
+            wantCode: http.StatusOK,
+            wantBody: "An old <mark>silent</mark> pond...",
+        },
+        {
+            name:     "No match",
+            urlPath:  "/search?q=frog",
+            wantCode: http.StatusOK,
+            wantBody: "No snippets matched",
+        },
+        {
+            name:     "Invalid page",
+            urlPath:  "/search?q=silent&page=0",
+            wantCode: http.StatusBadRequest,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            code, _, body := ts.get(t, tt.urlPath)
+
+            assert.Equal(t, code, tt.wantCode)
+
+            if tt.wantBody != "" {
+                assert.StringContains(t, body, tt.wantBody)
+            }
+        })
+    }
+}
+
+func TestSnippetCreate(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    t.Run("Unauthenticated", func(t *testing.T) {
+        code, headers, _ := ts.get(t, "/snippet/create")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, headers.Get("Location"), "/user/login")
+    })
+
+    t.Run("Authenticated", func(t *testing.T) {
+        ts.login(t)
+
+        code, _, body := ts.get(t, "/snippet/create")
+
+        assert.Equal(t, code, http.StatusOK)
+        assert.StringContains(t, body, "<form action='/snippet/create' method='POST'>")
+
+        form := url.Values{}
+        form.Add("title", "O snail")
+        form.Add("content", "O snail\nClimb Mount Fuji,\nBut slowly, slowly!")
+        form.Add("visibility", "public")
+        form.Add("expires", "1w")
+        form.Add("csrf_token", extractCSRFToken(t, body))
+
+        code, headers, _ := ts.postForm(t, "/snippet/create", form)
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, headers.Get("Location"), "/snippet/view/fuji234567")
+
+        expiries := []struct {
+            expires   string
+            expiresIn string
+            expiresOn string
+            wantCode  int
+        }{
+            {expires: "never", wantCode: http.StatusSeeOther},
+            {expires: "custom", expiresIn: "36h", wantCode: http.StatusSeeOther},
+            {expires: "custom", expiresIn: "soon", wantCode: http.StatusUnprocessableEntity},
+            {expires: "custom", expiresIn: "20y", wantCode: http.StatusUnprocessableEntity},
+            {expires: "date", expiresOn: time.Now().AddDate(0, 1, 0).Format("2006-01-02"), wantCode: http.StatusSeeOther},
+            {expires: "date", expiresOn: "2001-01-01", wantCode: http.StatusUnprocessableEntity},
+            // there is no expiry to keep for a new snippet
+            {expires: "keep", wantCode: http.StatusUnprocessableEntity},
+        }
+
+        for _, e := range expiries {
+            form.Set("expires", e.expires)
+            form.Set("expires_in", e.expiresIn)
+            form.Set("expires_on", e.expiresOn)
+
+            code, _, _ := ts.postForm(t, "/snippet/create", form)
+            if code != e.wantCode {
+                t.Errorf("expires=%q in=%q on=%q: got %d; want %d", e.expires, e.expiresIn, e.expiresOn, code, e.wantCode)
+            }
+        }
+
+        form.Set("expires", "1w")
+
+        form.Set("language", "klingon")
+        code, _, body = ts.postForm(t, "/snippet/create", form)
+
+        assert.Equal(t, code, http.StatusUnprocessableEntity)
+        assert.StringContains(t, body, "This field must be a supported language")
+    })
+}
+
+func TestSnippetEdit(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    code, headers, _ := ts.get(t, "/snippet/edit/pond234567")
+    assert.Equal(t, code, http.StatusSeeOther)
+    assert.Equal(t, headers.Get("Location"), "/user/login")
+
+    ts.login(t)
+
+    tests := []struct {
+        name     string
+        urlPath  string
+        title    string
+        wantCode int
+        wantLoc  string
+    }{
+        {
+            name:     "Owner",
+            urlPath:  "/snippet/edit/pond234567",
+            title:    "An old silent pond, revisited",
+            wantCode: http.StatusSeeOther,
+            wantLoc:  "/snippet/view/pond234567",
+        },
+        {
+            name:     "Blank title",
+            urlPath:  "/snippet/edit/pond234567",
+            title:    "",
+            wantCode: http.StatusUnprocessableEntity,
+        },
+        {
+            name:     "Not owner",
+            urlPath:  "/snippet/edit/forest2345",
+            title:    "Hijacked",
+            wantCode: http.StatusForbidden,
+        },
+        {
+            name:     "Non-existent ID",
+            urlPath:  "/snippet/edit/2",
+            title:    "Missing",
+            wantCode: http.StatusNotFound,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, _, body := ts.get(t, "/snippet/edit/pond234567")
+
+            form := url.Values{}
+            form.Add("title", tt.title)
+            form.Add("content", "An old silent pond...")
+            form.Add("visibility", "unlisted")
+            form.Add("expires", "keep")
+            form.Add("csrf_token", extractCSRFToken(t, body))
+
+            code, headers, _ := ts.postForm(t, tt.urlPath, form)
+
+            assert.Equal(t, code, tt.wantCode)
+            assert.Equal(t, headers.Get("Location"), tt.wantLoc)
+        })
+    }
+}
+
+func TestSnippetDelete(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    ts.login(t)
+
+    tests := []struct {
+        name     string
+        urlPath  string
+        wantCode int
+    }{
+        {
+            name:     "Owner",
+            urlPath:  "/snippet/delete/pond234567",
+            wantCode: http.StatusSeeOther,
+        },
+        {
+            name:     "Not owner",
+            urlPath:  "/snippet/delete/forest2345",
+            wantCode: http.StatusForbidden,
+        },
+        {
+            name:     "Non-existent ID",
+            urlPath:  "/snippet/delete/2",
+            wantCode: http.StatusNotFound,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, _, body := ts.get(t, "/snippet/view/pond234567")
+
+            form := url.Values{}
+            form.Add("csrf_token", extractCSRFToken(t, body))
+
+            code, _, _ := ts.postForm(t, tt.urlPath, form)
+
+            assert.Equal(t, code, tt.wantCode)
+        })
+    }
+}
+
 func TestUserSignup(t *testing.T) {
     app := newTestApplication(t)
     ts := newTestServer(t, app.routes())
@@ -192,3 +827,84 @@ func TestUserSignup(t *testing.T) {
         })
     }
 }
+
+func TestAccount(t *testing.T) {
+    app := newTestApplication(t)
+    ts := newTestServer(t, app.routes())
+    defer ts.Close()
+
+    t.Run("Unauthenticated", func(t *testing.T) {
+        code, headers, _ := ts.get(t, "/account")
+
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, headers.Get("Location"), "/user/login")
+    })
+
+    ts.login(t)
+
+    code, _, body := ts.get(t, "/account")
+    assert.Equal(t, code, http.StatusOK)
+    assert.StringContains(t, body, "<code>sbx_writewri…</code>")
+    assert.StringContains(t, body, "<td>Read only</td>")
+
+    csrfToken := extractCSRFToken(t, body)
+
+    tests := []struct {
+        name      string
+        tokenName string
+        scope     string
+        wantCode  int
+        wantBody  string
+    }{
+        {
+            name:      "Valid",
+            tokenName: "Editor",
+            scope:     "write",
+            wantCode:  http.StatusOK,
+            wantBody:  "<code>sbx_new2new2new2new2new2new2new2new2new2new2</code>",
+        },
+        {
+            name:      "Blank name",
+            tokenName: "",
+            scope:     "read",
+            wantCode:  http.StatusUnprocessableEntity,
+            wantBody:  "This field cannot be blank",
+        },
+        {
+            name:      "Invalid scope",
+            tokenName: "Editor",
+            scope:     "admin",
+            wantCode:  http.StatusUnprocessableEntity,
+            wantBody:  "This field must equal read or write",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            form := url.Values{}
+            form.Add("name", tt.tokenName)
+            form.Add("scope", tt.scope)
+            form.Add("csrf_token", csrfToken)
+
+            code, _, body := ts.postForm(t, "/account/tokens", form)
+
+            assert.Equal(t, code, tt.wantCode)
+            assert.StringContains(t, body, tt.wantBody)
+        })
+    }
+
+    t.Run("Revoke", func(t *testing.T) {
+        form := url.Values{}
+        form.Add("csrf_token", csrfToken)
+
+        code, headers, _ := ts.postForm(t, "/account/tokens/revoke/1", form)
+        assert.Equal(t, code, http.StatusSeeOther)
+        assert.Equal(t, headers.Get("Location"), "/account")
+
+        code, _, _ = ts.postForm(t, "/account/tokens/revoke/99", form)
+        assert.Equal(t, code, http.StatusNotFound)
+
+        code, _, _ = ts.postForm(t, "/account/tokens/revoke/x", form)
+        assert.Equal(t, code, http.StatusNotFound)
+    })
+}
@@ -4,16 +4,32 @@ import (
     "bytes"
     "errors"
     "fmt"
+    "net"
     "net/http"
+    "net/url"
+    "strconv"
+    "strings"
     "time"
+    "unicode"
+
+    "github.com/j-clemons/snippetbox/internal/models"
+    "github.com/j-clemons/snippetbox/internal/syntax"
 
     "github.com/go-playground/form/v4"
+    "github.com/julienschmidt/httprouter"
     "github.com/justinas/nosurf"
 )
 
 // the serverError helper writes a log entry at Error level
-// then sends a generic 500 Interal Server Error response
+// then sends a generic 500 Interal Server Error response.
+// Errors from a database that took too long are passed on to
+// unavailable() instead
 func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error) {
+    if models.IsTimeout(err) {
+        app.unavailable(w, r, err)
+        return
+    }
+
     var (
         method = r.Method
         uri = r.URL.RequestURI()
@@ -23,6 +39,20 @@ func (app *application) serverError(w http.ResponseWriter, r *http.Request, err
     http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 }
 
+// the unavailable helper sends a 503 Service Unavailable response for a
+// request that ran out of time waiting for the database. That is usually
+// a passing problem rather than a bug, so it is logged as a warning and the
+// client is told when to try again
+func (app *application) unavailable(w http.ResponseWriter, r *http.Request, err error) {
+    app.logger.Warn(err.Error(), "method", r.Method, "uri", r.URL.RequestURI())
+
+    w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+    http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// how long clients are asked to wait before retrying after a 503
+const retryAfterSeconds = 5
+
 // the clientError helper send a specific status code and description
 func (app *application) clientError(w http.ResponseWriter, status int) {
     http.Error(w, http.StatusText(status), status)
@@ -71,6 +101,7 @@ func (app *application) newTemplateData(r *http.Request) templateData {
         CurrentYear:     time.Now().Year(),
         Flash:           app.sessionManager.PopString(r.Context(), "flash"),
         IsAuthenticated: app.isAuthenticated(r),
+        AuthenticatedID: app.authenticatedUserID(r),
         CSRFToken:       nosurf.Token(r),
     }
 }
@@ -105,3 +136,247 @@ func (app *application) isAuthenticated(r *http.Request) bool {
 
     return isAuthenticated
 }
+
+// authenticatedUserID returns the ID of the logged in user, or 0 if
+// the request is not authenticated
+func (app *application) authenticatedUserID(r *http.Request) int {
+    if !app.isAuthenticated(r) {
+        return 0
+    }
+
+    return app.sessionManager.GetInt(r.Context(), "authenticatedUserID")
+}
+
+// lookupSnippet fetches the snippet named by the id route parameter, which
+// holds the snippet's slug. If the slug is invalid, the snippet does not
+// exist or it is private and the current user is not its owner, the
+// appropriate error response is sent and ok is false, so callers should
+// simply return. Legacy numeric ids of public snippets are permanently
+// redirected to the same URL with the slug in their place
+func (app *application) lookupSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+    params := httprouter.ParamsFromContext(r.Context())
+    param := params.ByName("id")
+
+    if id, err := strconv.Atoi(param); err == nil {
+        app.redirectLegacySnippet(w, r, id, param)
+        return models.Snippet{}, false
+    }
+
+    if !models.SlugRX.MatchString(param) {
+        app.notFound(w)
+        return models.Snippet{}, false
+    }
+
+    snippet, err := app.snippets.GetBySlug(r.Context(), param)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.notFound(w)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return models.Snippet{}, false
+    }
+
+    // respond as if private snippets do not exist, so that their ids
+    // cannot be discovered by anyone but the owner
+    if snippet.Visibility == models.VisibilityPrivate && snippet.UserID != app.authenticatedUserID(r) {
+        app.notFound(w)
+        return models.Snippet{}, false
+    }
+
+    return snippet, true
+}
+
+// readableSnippet works like lookupSnippet but also sends a 404 for burn
+// after reading snippets, and a 403 Forbidden for locked snippets that have
+// not been unlocked in this session. It is used by the handlers that would
+// otherwise give away the content of such a snippet
+func (app *application) readableSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+    snippet, ok := app.lookupSnippet(w, r)
+    if !ok {
+        return models.Snippet{}, false
+    }
+
+    if snippet.BurnAfterReading {
+        app.notFound(w)
+        return models.Snippet{}, false
+    }
+
+    if !app.isUnlocked(r, snippet) {
+        app.clientError(w, http.StatusForbidden)
+        return models.Snippet{}, false
+    }
+
+    return snippet, true
+}
+
+// isUnlocked reports whether the content of a snippet may be shown. That
+// is always the case for snippets without a passphrase and for the owner;
+// anyone else has to have entered the passphrase earlier in their session
+func (app *application) isUnlocked(r *http.Request, snippet models.Snippet) bool {
+    if !snippet.Locked || snippet.UserID == app.authenticatedUserID(r) {
+        return true
+    }
+
+    return app.sessionManager.GetBool(r.Context(), unlockedKey(snippet))
+}
+
+// unlockedKey is the session key recording that a snippet was unlocked
+func unlockedKey(snippet models.Snippet) string {
+    return fmt.Sprintf("unlockedSnippet:%d", snippet.ID)
+}
+
+// redirectLegacySnippet handles URLs from before snippets had slugs, which
+// named them by their auto-increment id. Only public snippets are
+// redirected, since their ids were never a secret; for anything else we
+// send a 404 so that ids cannot be used to find unlisted snippets
+func (app *application) redirectLegacySnippet(w http.ResponseWriter, r *http.Request, id int, param string) {
+    if id < 1 || r.Method != http.MethodGet {
+        app.notFound(w)
+        return
+    }
+
+    snippet, err := app.snippets.Get(r.Context(), id)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.notFound(w)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return
+    }
+
+    if snippet.Visibility != models.VisibilityPublic {
+        app.notFound(w)
+        return
+    }
+
+    // swap the id segment of the path for the slug, keeping the rest of
+    // the URL so that links to the raw content or history still work
+    segments := strings.Split(r.URL.Path, "/")
+    for i := range segments {
+        if segments[i] == param {
+            segments[i] = snippet.Slug
+            break
+        }
+    }
+
+    target := url.URL{Path: strings.Join(segments, "/"), RawQuery: r.URL.RawQuery}
+
+    http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+}
+
+// lookupRevision fetches a single revision of a snippet. If there is no
+// such revision a 404 is sent and ok is false, as for lookupSnippet
+func (app *application) lookupRevision(w http.ResponseWriter, r *http.Request, snippetID int, version int) (models.Revision, bool) {
+    revision, err := app.snippets.GetRevision(r.Context(), snippetID, version)
+    if err != nil {
+        if errors.Is(err, models.ErrNoRecord) {
+            app.notFound(w)
+        } else {
+            app.serverError(w, r, err)
+        }
+        return models.Revision{}, false
+    }
+
+    return revision, true
+}
+
+// ownedSnippet works like lookupSnippet but also checks that the snippet
+// belongs to the logged in user, sending a 403 Forbidden if it does not
+func (app *application) ownedSnippet(w http.ResponseWriter, r *http.Request) (models.Snippet, bool) {
+    snippet, ok := app.lookupSnippet(w, r)
+    if !ok {
+        return models.Snippet{}, false
+    }
+
+    if snippet.UserID != app.authenticatedUserID(r) {
+        app.clientError(w, http.StatusForbidden)
+        return models.Snippet{}, false
+    }
+
+    return snippet, true
+}
+
+// clientIP returns the address of the client that made the request,
+// without the port
+func clientIP(r *http.Request) string {
+    host, _, err := net.SplitHostPort(r.RemoteAddr)
+    if err != nil {
+        return r.RemoteAddr
+    }
+
+    return host
+}
+
+// httpsURL returns the URL of the request with the scheme changed to
+// https and the port to the given one, which is left out when blank
+func httpsURL(r *http.Request, port string) string {
+    host := r.Host
+    if h, _, err := net.SplitHostPort(host); err == nil {
+        host = h
+    }
+
+    if port != "" && port != "443" {
+        host = net.JoinHostPort(host, port)
+    }
+
+    return "https://" + host + r.URL.RequestURI()
+}
+
+// queryInt reads an integer from the URL query string, returning def if
+// the key is not present
+func queryInt(r *http.Request, key string, def int) (int, error) {
+    value := r.URL.Query().Get(key)
+    if value == "" {
+        return def, nil
+    }
+
+    return strconv.Atoi(value)
+}
+
+// snippetFilename turns the title of a snippet into a filename for
+// downloads, such as "an-old-silent-pond.txt". Characters other than
+// letters and digits are collapsed into single dashes
+func snippetFilename(snippet models.Snippet) string {
+    var b strings.Builder
+
+    dash := false
+    for _, r := range strings.ToLower(snippet.Title) {
+        if unicode.IsLetter(r) || unicode.IsDigit(r) {
+            if dash && b.Len() > 0 {
+                b.WriteRune('-')
+            }
+            b.WriteRune(r)
+            dash = false
+        } else {
+            dash = true
+        }
+    }
+
+    name := b.String()
+    if name == "" {
+        name = fmt.Sprintf("snippet-%d", snippet.ID)
+    }
+
+    return name + syntax.Extension(snippet.Language)
+}
+
+// background runs fn in a new goroutine tracked by the application's
+// WaitGroup, so that shutdown waits for it. A panic in fn is logged
+// rather than bringing down the whole server
+func (app *application) background(fn func()) {
+    app.wg.Add(1)
+
+    go func() {
+        defer app.wg.Done()
+
+        defer func() {
+            if err := recover(); err != nil {
+                app.logger.Error(fmt.Sprintf("%s", err))
+            }
+        }()
+
+        fn()
+    }()
+}
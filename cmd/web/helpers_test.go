@@ -0,0 +1,29 @@
+package main
+
+import (
+    "sync/atomic"
+    "testing"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestBackground(t *testing.T) {
+    app := newTestApplication(t)
+
+    var ran atomic.Int32
+
+    for i := 0; i < 3; i++ {
+        app.background(func() {
+            ran.Add(1)
+        })
+    }
+
+    // a panicking goroutine is still counted as done
+    app.background(func() {
+        panic("oops")
+    })
+
+    app.wg.Wait()
+
+    assert.Equal(t, ran.Load(), int32(3))
+}
@@ -1,49 +1,63 @@
 package main
 
 import (
+    "context"
     "crypto/tls"
-    "database/sql"
+    "errors"
     "flag"
+    "fmt"
     "html/template"
+    "log"
     "log/slog"
     "net/http"
     "os"
-    "time"
+    "os/signal"
+    "sync"
+    "syscall"
 
+    "github.com/j-clemons/snippetbox/internal/migrations"
     "github.com/j-clemons/snippetbox/internal/models"
 
-    "github.com/alexedwards/scs/mysqlstore"
     "github.com/alexedwards/scs/v2"
     "github.com/go-playground/form/v4"
-    _ "github.com/go-sql-driver/mysql"
 )
 
 // define an application struct to hold the app-wide dependencies
 type application struct {
+    config         config
     logger         *slog.Logger
     snippets       models.SnippetModelInterface
     users          models.UserModelInterface
+    tokens         models.TokenModelInterface
     templateCache  map[string]*template.Template
     formDecoder    *form.Decoder
     sessionManager *scs.SessionManager
+    unlockThrottle *throttle
+    // wg tracks the goroutines started with background(), so that
+    // shutdown can wait for them to finish
+    wg             sync.WaitGroup
 }
 
 func main() {
-    // define command line flag with name 'addr' 
-    // default to 4000
-    addr := flag.String("addr", ":4000", "HTTP network address")
-
-    // define a new command line flag for the MySQL DSN String
-    dsn := flag.String("dsn", "web:1234@/snippetbox?parseTime=true", "MySQL data source name")
+    logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-    // must parse the flag first so it can read the flag and assign
-    // to the variable. Must be called *before* using the addr var or it
-    // will just be the default. If it errors application will be terminated
-    flag.Parse()
+    // the session stores report errors from their cleanup goroutines
+    // with the standard log package, so send its output through logger
+    log.SetFlags(0)
+    log.SetOutput(slog.NewLogLogger(logger.Handler(), slog.LevelError).Writer())
 
-    logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+    // read the settings from the config file, environment and command
+    // line flags. See config.go for which source wins
+    cfg, err := loadConfig(os.Args[1:], os.Getenv)
+    if err != nil {
+        if errors.Is(err, flag.ErrHelp) {
+            return
+        }
+        logger.Error(err.Error())
+        os.Exit(1)
+    }
 
-    db, err := openDB(*dsn)
+    db, dialect, err := openDB(cfg.DSN)
     if err != nil {
         logger.Error(err.Error())
         os.Exit(1)
@@ -53,6 +67,35 @@ func main() {
     // before the main() function exits
     defer db.Close()
 
+    migrator, err := migrations.New(db, dialect)
+    if err != nil {
+        logger.Error(err.Error())
+        os.Exit(1)
+    }
+
+    // the only subcommand is migrate, which manages the schema and exits
+    if len(cfg.args) > 0 {
+        if cfg.args[0] != "migrate" {
+            logger.Error(fmt.Sprintf("unknown command %q", cfg.args[0]))
+            os.Exit(1)
+        }
+
+        err = runMigrate(migrator, cfg.args[1:], os.Stdout)
+        if err != nil {
+            logger.Error(err.Error())
+            os.Exit(1)
+        }
+        return
+    }
+
+    // refuse to run against a schema older than the code expects, which
+    // would fail in confusing ways as soon as a missing column is used
+    err = migrator.Check()
+    if err != nil {
+        logger.Error(err.Error(), "hint", "run the migrate up command")
+        os.Exit(1)
+    }
+
     // initialize new template cache
     templateCache, err := newTemplateCache()
     if err != nil {
@@ -62,65 +105,117 @@ func main() {
 
     formDecoder := form.NewDecoder()
 
+    // the models and the session store are those for the database the
+    // DSN points at
+    snippets, users, tokens, sessionStore := newStores(db, dialect, cfg.BcryptCost, cfg.QueryTimeout)
+
     // use the scs.New() to initialize a new session manager.
-    // configure it to use mysql DB as the session store
-    // set session lifetime of 12 hours
+    // configure it to keep sessions in the database
+    // set session lifetime from the config
     sessionManager := scs.New()
-    sessionManager.Store = mysqlstore.New(db)
-    sessionManager.Lifetime = 12 * time.Hour
+    sessionManager.Store = sessionStore
+    sessionManager.Lifetime = cfg.Session.Lifetime
+    // cookies stay Secure even without TLS, since then the server is behind
+    // a proxy that serves HTTPS to the browser
     sessionManager.Cookie.Secure = true
 
     // initialize a new instance of the application struct
     // containing the dependencies
     app := &application{
         logger:         logger,
-        snippets:       &models.SnippetModel{DB: db},
-        users:          &models.UserModel{DB: db},
+        config:         cfg,
+        snippets:       snippets,
+        users:          users,
+        tokens:         tokens,
         templateCache:  templateCache,
         formDecoder:    formDecoder,
         sessionManager: sessionManager,
+        unlockThrottle: newThrottle(unlockAttempts, unlockWindow),
+    }
+
+    if cfg.purge {
+        n, err := app.purgeExpired(context.Background(), cfg.Reaper)
+        if err != nil {
+            logger.Error(err.Error(), "purged", n)
+            os.Exit(1)
+        }
+
+        logger.Info("purged expired snippets", "count", n, "archive", cfg.Reaper.Archive)
+        return
     }
 
+    // ctx is cancelled when the process is asked to stop, which begins a
+    // graceful shutdown of the server and the background goroutines
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+    defer stop()
+
+    // SIGHUP asks for the TLS certificate to be reloaded. It is caught
+    // whether or not TLS is enabled, since by default it would kill the
+    // server. Without TLS nothing reads from hup, so it is ignored
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
+    defer signal.Stop(hup)
+
+    if cfg.Reaper.Interval > 0 {
+        app.background(func() {
+            app.reap(ctx, cfg.Reaper)
+        })
+    }
+
+    // the session store deletes expired sessions in a goroutine of its
+    // own, which we stop before the database is closed
+    app.background(func() {
+        <-ctx.Done()
+        sessionStore.StopCleanup()
+    })
+
     // initialize a tls.Config struct to hold the non-default tls
     // settings we want the server to use. 
     tlsConfig := &tls.Config{
         CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
     }
 
+    // the certificate is served through GetCertificate so that it can be
+    // reloaded without a restart when it is renewed
+    if cfg.TLS.Enabled {
+        certs, err := newCertReloader(cfg.TLS.Cert, cfg.TLS.Key, logger)
+        if err != nil {
+            logger.Error(err.Error())
+            os.Exit(1)
+        }
+        tlsConfig.GetCertificate = certs.GetCertificate
+
+        app.background(func() {
+            certs.watch(ctx, hup, cfg.TLS.ReloadInterval)
+        })
+    }
+
     srv := &http.Server{
-        Addr:         *addr,
+        Addr:         cfg.Addr,
         Handler:      app.routes(),
         ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
         TLSConfig:    tlsConfig,
-        IdleTimeout:  time.Minute,
-        ReadTimeout:  5 * time.Second,
-        WriteTimeout: 10 * time.Second,
+        IdleTimeout:  cfg.Server.IdleTimeout,
+        ReadTimeout:  cfg.Server.ReadTimeout,
+        WriteTimeout: cfg.Server.WriteTimeout,
     }
 
-    // Print a log message to say that the server is starting.
-    logger.Info("starting server", "addr", srv.Addr)
-
-    // Use the http.ListenAndServe() function to start a new web server.
-    // We pass in two params: the TCP network address to list to no (ex. :4000)
-    // and the servemux we just created. If http.ListenAndServe() returns an 
-    // error we use the log.Fatal() to log the error and exit.main
-    // Note any error returned by http.ListenAndServe() is always non-nil.
-    // err = http.ListenAndServe(*addr, app.routes())
-
-    err = srv.ListenAndServeTLS("./tls/cert.pem", "./tls/key.pem")
-    logger.Error(err.Error())
-    os.Exit(1)
-}
+    // optionally listen for plain HTTP as well, just to redirect it to HTTPS
+    var redirect *http.Server
+    if cfg.TLS.RedirectAddr != "" {
+        redirect = &http.Server{
+            Addr:         cfg.TLS.RedirectAddr,
+            Handler:      app.redirectRoutes(),
+            ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelError),
+            IdleTimeout:  cfg.Server.IdleTimeout,
+            ReadTimeout:  cfg.Server.ReadTimeout,
+            WriteTimeout: cfg.Server.WriteTimeout,
+        }
+    }
 
-// the openDB() func wraps sql.Open() and returns a sql.DB connection
-// pool for a given DSN.
-func openDB(dsn string) (*sql.DB, error) {
-    db, err := sql.Open("mysql", dsn)
+    err = app.serve(ctx, srv, redirect)
     if err != nil {
-        return nil, err
-    }
-    if err = db.Ping(); err != nil {
-        return nil, err
+        logger.Error(err.Error())
+        os.Exit(1)
     }
-    return db, nil
 }
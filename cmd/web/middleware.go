@@ -2,8 +2,13 @@ package main
 
 import (
     "context"
+    "errors"
     "fmt"
     "net/http"
+    "net/netip"
+    "strings"
+
+    "github.com/j-clemons/snippetbox/internal/models"
 
     "github.com/justinas/nosurf"
 )
@@ -25,7 +30,7 @@ func secureHeaders(next http.Handler) http.Handler {
 func (app *application) logRequest(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         var (
-            ip     = r.RemoteAddr
+            ip     = clientIP(r)
             proto  = r.Proto
             method = r.Method
             uri    = r.URL.RequestURI()
@@ -37,6 +42,63 @@ func (app *application) logRequest(next http.Handler) http.Handler {
     })
 }
 
+// proxyHeaders handles requests that come through one of the trusted
+// reverse proxies. X-Forwarded-For is used to find the real client, whose
+// address replaces r.RemoteAddr so that logging and throttling see it,
+// and requests the proxy received over plain HTTP are redirected to HTTPS.
+// The headers of anyone else are ignored, since they could be forged
+func proxyHeaders(trusted []netip.Prefix) func(http.Handler) http.Handler {
+    isTrusted := func(addr netip.Addr) bool {
+        for _, prefix := range trusted {
+            if prefix.Contains(addr.Unmap()) {
+                return true
+            }
+        }
+        return false
+    }
+
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            peer, err := netip.ParseAddr(clientIP(r))
+            if err != nil || !isTrusted(peer) {
+                next.ServeHTTP(w, r)
+                return
+            }
+
+            if strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "http") {
+                http.Redirect(w, r, httpsURL(r, ""), http.StatusPermanentRedirect)
+                return
+            }
+
+            // each proxy appends the address it received the request from,
+            // so walking from the right the first address that is not one
+            // of our proxies is the client
+            client := peer
+            hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+            for i := len(hops) - 1; i >= 0; i-- {
+                hop := strings.TrimSpace(hops[i])
+                if hop == "" {
+                    continue
+                }
+
+                addr, err := netip.ParseAddr(hop)
+                if err != nil {
+                    break
+                }
+
+                client = addr
+                if !isTrusted(addr) {
+                    break
+                }
+            }
+
+            r.RemoteAddr = client.String()
+
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
 func (app *application) recoverPanic(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         // create a deferred func that runs in event of panic 
@@ -86,7 +148,7 @@ func (app *application) authenticate(next http.Handler) http.Handler {
             return
         }
 
-        exists, err := app.users.Exists(id)
+        exists, err := app.users.Exists(r.Context(), id)
         if err != nil {
             app.serverError(w, r, err)
             return
@@ -100,3 +162,66 @@ func (app *application) authenticate(next http.Handler) http.Handler {
         next.ServeHTTP(w, r)
     })
 }
+
+// authenticateToken is the API's counterpart to authenticate. Requests
+// carrying a personal API token in an Authorization: Bearer header are
+// authenticated as the token's owner. Requests without one carry on
+// anonymously, but a bad token is refused rather than quietly ignored, so
+// that mistakes in scripts show up
+func (app *application) authenticateToken(next http.Handler) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        header := r.Header.Get("Authorization")
+        if header == "" {
+            next.ServeHTTP(w, r)
+            return
+        }
+
+        scheme, value, _ := strings.Cut(header, " ")
+        value = strings.TrimSpace(value)
+        if !strings.EqualFold(scheme, "Bearer") || !models.TokenRX.MatchString(value) {
+            app.apiUnauthorized(w, "invalid_token")
+            return
+        }
+
+        token, err := app.tokens.Authenticate(r.Context(), value)
+        if err != nil {
+            if errors.Is(err, models.ErrInvalidCredentials) {
+                app.apiUnauthorized(w, "invalid_token")
+            } else {
+                app.apiServerError(w, r, err)
+            }
+            return
+        }
+
+        ctx := context.WithValue(r.Context(), apiTokenContextKey, token)
+        r = r.WithContext(ctx)
+
+        // responses for a particular user must not be kept by caches
+        w.Header().Add("Cache-Control", "no-store")
+
+        next.ServeHTTP(w, r)
+    })
+}
+
+// requireScope sends a 401 Unauthorized for API requests without a token,
+// and a 403 Forbidden for those whose token does not have the given scope.
+// Write tokens can also do anything read tokens can
+func (app *application) requireScope(scope string) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            token, ok := app.apiToken(r)
+            if !ok {
+                app.apiUnauthorized(w, "")
+                return
+            }
+
+            if token.Scope != scope && token.Scope != models.ScopeWrite {
+                w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="snippetbox", error="insufficient_scope", scope=%q`, scope))
+                app.apiClientError(w, http.StatusForbidden)
+                return
+            }
+
+            next.ServeHTTP(w, r)
+        })
+    }
+}
@@ -5,6 +5,7 @@ import (
     "io"
     "net/http"
     "net/http/httptest"
+    "net/netip"
     "testing"
 
     "github.com/j-clemons/snippetbox/internal/assert"
@@ -52,3 +53,118 @@ func TestSecureHeaders(t *testing.T) {
 
     assert.Equal(t, string(body), "OK")
 }
+
+func TestProxyHeaders(t *testing.T) {
+    trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+    tests := []struct {
+        name           string
+        remoteAddr     string
+        forwardedFor   string
+        forwardedProto string
+        wantCode       int
+        wantAddr       string
+        wantLocation   string
+    }{
+        {
+            name:       "Direct",
+            remoteAddr: "198.51.100.7:51234",
+            wantCode:   http.StatusOK,
+            wantAddr:   "198.51.100.7:51234",
+        },
+        {
+            name:         "Untrusted peer",
+            remoteAddr:   "198.51.100.7:51234",
+            forwardedFor: "203.0.113.9",
+            wantCode:     http.StatusOK,
+            wantAddr:     "198.51.100.7:51234",
+        },
+        {
+            name:           "Trusted proxy",
+            remoteAddr:     "10.0.0.2:51234",
+            forwardedFor:   "203.0.113.9",
+            forwardedProto: "https",
+            wantCode:       http.StatusOK,
+            wantAddr:       "203.0.113.9",
+        },
+        {
+            name:         "Chain of proxies",
+            remoteAddr:   "10.0.0.2:51234",
+            forwardedFor: "192.0.2.66, 203.0.113.9, 10.0.0.3",
+            wantCode:     http.StatusOK,
+            wantAddr:     "203.0.113.9",
+        },
+        {
+            name:           "Plain HTTP through proxy",
+            remoteAddr:     "10.0.0.2:51234",
+            forwardedFor:   "203.0.113.9",
+            forwardedProto: "http",
+            wantCode:       http.StatusPermanentRedirect,
+            wantLocation:   "https://example.com/snippet/view/pond234567?x=1",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            rr := httptest.NewRecorder()
+
+            r := httptest.NewRequest(http.MethodGet, "http://example.com/snippet/view/pond234567?x=1", nil)
+            r.RemoteAddr = tt.remoteAddr
+            if tt.forwardedFor != "" {
+                r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+            }
+            if tt.forwardedProto != "" {
+                r.Header.Set("X-Forwarded-Proto", tt.forwardedProto)
+            }
+
+            var gotAddr string
+            next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                gotAddr = r.RemoteAddr
+            })
+
+            proxyHeaders(trusted)(next).ServeHTTP(rr, r)
+
+            assert.Equal(t, rr.Code, tt.wantCode)
+            assert.Equal(t, gotAddr, tt.wantAddr)
+            assert.Equal(t, rr.Header().Get("Location"), tt.wantLocation)
+        })
+    }
+}
+
+func TestRedirectRoutes(t *testing.T) {
+    app := newTestApplication(t)
+
+    tests := []struct {
+        name   string
+        addr   string
+        target string
+        want   string
+    }{
+        {
+            name:   "Default port",
+            addr:   ":443",
+            target: "http://example.com/snippet/view/pond234567",
+            want:   "https://example.com/snippet/view/pond234567",
+        },
+        {
+            name:   "Other port",
+            addr:   ":4000",
+            target: "http://example.com:80/search?q=pond",
+            want:   "https://example.com:4000/search?q=pond",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            app.config.Addr = tt.addr
+
+            rr := httptest.NewRecorder()
+            r := httptest.NewRequest(http.MethodPost, tt.target, nil)
+
+            app.redirectRoutes().ServeHTTP(rr, r)
+
+            assert.Equal(t, rr.Code, http.StatusPermanentRedirect)
+            assert.Equal(t, rr.Header().Get("Location"), tt.want)
+        })
+    }
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+    "errors"
+    "fmt"
+    "io"
+    "strconv"
+    "text/tabwriter"
+
+    "github.com/j-clemons/snippetbox/internal/migrations"
+)
+
+// errMigrateUsage is returned for a migrate command that is not understood
+var errMigrateUsage = errors.New("usage: migrate up | down [steps] | status")
+
+// runMigrate carries out the migrate subcommand given by args, such as
+// "up" or "down 2", writing what it did to w
+func runMigrate(m *migrations.Migrator, args []string, w io.Writer) error {
+    if len(args) == 0 {
+        return errMigrateUsage
+    }
+
+    switch args[0] {
+    case "up":
+        if len(args) > 1 {
+            return errMigrateUsage
+        }
+
+        done, err := m.Up()
+        for _, migration := range done {
+            fmt.Fprintf(w, "applied %04d %s\n", migration.Version, migration.Name)
+        }
+        if err != nil {
+            return err
+        }
+
+        if len(done) == 0 {
+            fmt.Fprintln(w, "database is up to date")
+        }
+    case "down":
+        // only revert one migration unless told otherwise, since going
+        // down usually throws data away
+        steps := 1
+        if len(args) > 2 {
+            return errMigrateUsage
+        }
+        if len(args) == 2 {
+            n, err := strconv.Atoi(args[1])
+            if err != nil || n < 1 {
+                return errMigrateUsage
+            }
+            steps = n
+        }
+
+        done, err := m.Down(steps)
+        for _, migration := range done {
+            fmt.Fprintf(w, "reverted %04d %s\n", migration.Version, migration.Name)
+        }
+        if err != nil {
+            return err
+        }
+
+        if len(done) == 0 {
+            fmt.Fprintln(w, "no migrations to revert")
+        }
+    case "status":
+        if len(args) > 1 {
+            return errMigrateUsage
+        }
+
+        statuses, err := m.Status()
+        if err != nil {
+            return err
+        }
+
+        tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+        fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+        for _, s := range statuses {
+            applied := "pending"
+            if !s.Applied.IsZero() {
+                applied = s.Applied.UTC().Format("2006-01-02 15:04:05")
+            }
+            fmt.Fprintf(tw, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+        }
+        return tw.Flush()
+    default:
+        return errMigrateUsage
+    }
+
+    return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+    "errors"
+    "io"
+    "testing"
+
+    "github.com/j-clemons/snippetbox/internal/migrations"
+)
+
+func TestRunMigrateUsage(t *testing.T) {
+    tests := []struct {
+        name string
+        args []string
+    }{
+        {name: "No command", args: nil},
+        {name: "Unknown command", args: []string{"sideways"}},
+        {name: "Up with steps", args: []string{"up", "2"}},
+        {name: "Down zero steps", args: []string{"down", "0"}},
+        {name: "Down not a number", args: []string{"down", "all"}},
+        {name: "Status with arguments", args: []string{"status", "verbose"}},
+    }
+
+    // none of these get as far as touching the database
+    m := &migrations.Migrator{}
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := runMigrate(m, tt.args, io.Discard)
+            if !errors.Is(err, errMigrateUsage) {
+                t.Errorf("got %v; want %v", err, errMigrateUsage)
+            }
+        })
+    }
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+    "context"
+    "time"
+)
+
+// reaperConfig controls how expired snippets are cleaned up
+type reaperConfig struct {
+    // how often the reaper runs. Zero turns the reaper off
+    Interval time.Duration `toml:"interval"`
+    // the most snippets removed in a single transaction
+    Batch    int           `toml:"batch"`
+    // copy snippets to the archive table instead of just deleting them
+    Archive  bool          `toml:"archive"`
+}
+
+// purgeExpired removes every expired snippet, a batch at a time so that no
+// single transaction holds locks on too many rows, and returns how many
+// were removed. It stops early if ctx is cancelled
+func (app *application) purgeExpired(ctx context.Context, cfg reaperConfig) (int, error) {
+    total := 0
+
+    for {
+        n, err := app.snippets.Purge(ctx, cfg.Batch, cfg.Archive)
+        total += n
+        if err != nil {
+            return total, err
+        }
+
+        // a short batch means there is nothing left to purge
+        if n < cfg.Batch {
+            return total, nil
+        }
+    }
+}
+
+// reap purges expired snippets every interval until ctx is cancelled
+func (app *application) reap(ctx context.Context, cfg reaperConfig) {
+    ticker := time.NewTicker(cfg.Interval)
+    defer ticker.Stop()
+
+    app.logger.Info("starting reaper", "interval", cfg.Interval, "batch", cfg.Batch, "archive", cfg.Archive)
+
+    for {
+        select {
+        case <-ctx.Done():
+            app.logger.Info("stopped reaper")
+            return
+        case <-ticker.C:
+            n, err := app.purgeExpired(ctx, cfg)
+            if err != nil {
+                app.logger.Error(err.Error(), "purged", n)
+                continue
+            }
+
+            if n > 0 {
+                app.logger.Info("purged expired snippets", "count", n, "archive", cfg.Archive)
+            }
+        }
+    }
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestPurgeExpired(t *testing.T) {
+    app := newTestApplication(t)
+
+    n, err := app.purgeExpired(context.Background(), reaperConfig{Batch: 10})
+
+    assert.NilError(t, err)
+    assert.Equal(t, n, 0)
+}
+
+func TestReapStops(t *testing.T) {
+    app := newTestApplication(t)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    done := make(chan struct{})
+
+    go func() {
+        defer close(done)
+        app.reap(ctx, reaperConfig{Interval: time.Millisecond, Batch: 10})
+    }()
+
+    // let the reaper run a few times before stopping it
+    time.Sleep(10 * time.Millisecond)
+    cancel()
+
+    select {
+    case <-done:
+    case <-time.After(time.Second):
+        t.Fatal("reaper did not stop after its context was cancelled")
+    }
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+    "net"
     "net/http"
 
+    "github.com/j-clemons/snippetbox/internal/models"
     "github.com/j-clemons/snippetbox/ui"
 
     "github.com/julienschmidt/httprouter"
@@ -15,9 +17,21 @@ func (app *application) routes() http.Handler {
     router := httprouter.New()
 
     router.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if isAPIRequest(r) {
+            app.apiNotFound(w)
+            return
+        }
         app.notFound(w)
     })
 
+    router.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if isAPIRequest(r) {
+            app.apiClientError(w, http.StatusMethodNotAllowed)
+            return
+        }
+        app.clientError(w, http.StatusMethodNotAllowed)
+    })
+
     // create a file server which serves files out of the "./ui/static"
     // dir. Note that the path given to the http.Dir func is relative
     // to the project directory root.
@@ -32,7 +46,15 @@ func (app *application) routes() http.Handler {
 
     // Register the other application routes as normal.
     router.Handler(http.MethodGet, "/", dynamic.ThenFunc(app.home))
+    router.Handler(http.MethodGet, "/snippets", dynamic.ThenFunc(app.snippetArchive))
+    router.Handler(http.MethodGet, "/search", dynamic.ThenFunc(app.search))
     router.Handler(http.MethodGet, "/snippet/view/:id", dynamic.ThenFunc(app.snippetView))
+    router.Handler(http.MethodPost, "/snippet/view/:id", dynamic.ThenFunc(app.snippetRevealPost))
+    router.Handler(http.MethodPost, "/snippet/unlock/:id", dynamic.ThenFunc(app.snippetUnlockPost))
+    router.Handler(http.MethodGet, "/snippet/view/:id/history", dynamic.ThenFunc(app.snippetHistory))
+    router.Handler(http.MethodGet, "/snippet/view/:id/diff", dynamic.ThenFunc(app.snippetDiff))
+    router.Handler(http.MethodGet, "/snippet/raw/:id", dynamic.ThenFunc(app.snippetRaw))
+    router.Handler(http.MethodGet, "/snippet/download/:id", dynamic.ThenFunc(app.snippetDownload))
     router.Handler(http.MethodGet, "/user/signup/", dynamic.ThenFunc(app.userSignup))
     router.Handler(http.MethodPost, "/user/signup/", dynamic.ThenFunc(app.userSignupPost))
     router.Handler(http.MethodGet, "/user/login/", dynamic.ThenFunc(app.userLogin))
@@ -42,10 +64,41 @@ func (app *application) routes() http.Handler {
 
     router.Handler(http.MethodGet, "/snippet/create", protected.ThenFunc(app.snippetCreate))
     router.Handler(http.MethodPost, "/snippet/create", protected.ThenFunc(app.snippetCreatePost))
+    router.Handler(http.MethodGet, "/snippet/edit/:id", protected.ThenFunc(app.snippetEdit))
+    router.Handler(http.MethodPost, "/snippet/edit/:id", protected.ThenFunc(app.snippetEditPost))
+    router.Handler(http.MethodPost, "/snippet/delete/:id", protected.ThenFunc(app.snippetDeletePost))
     router.Handler(http.MethodPost, "/user/logout/", protected.ThenFunc(app.userLogoutPost))
+    router.Handler(http.MethodGet, "/account", protected.ThenFunc(app.account))
+    router.Handler(http.MethodPost, "/account/tokens", protected.ThenFunc(app.accountTokenCreatePost))
+    router.Handler(http.MethodPost, "/account/tokens/revoke/:id", protected.ThenFunc(app.accountTokenRevokePost))
+
+    // the JSON API is used by scripts rather than browsers, so it has no
+    // session or CSRF token. Clients send an API token with every request
+    // instead, and only write tokens can change anything
+    api := alice.New(app.authenticateToken)
+    apiProtected := api.Append(app.requireScope(models.ScopeWrite))
+
+    router.Handler(http.MethodGet, apiPrefix+"/snippets", api.ThenFunc(app.apiSnippetList))
+    router.Handler(http.MethodPost, apiPrefix+"/snippets", apiProtected.ThenFunc(app.apiSnippetCreate))
+    router.Handler(http.MethodGet, apiPrefix+"/snippets/:id", api.ThenFunc(app.apiSnippetView))
+    router.Handler(http.MethodPatch, apiPrefix+"/snippets/:id", apiProtected.ThenFunc(app.apiSnippetUpdate))
+    router.Handler(http.MethodDelete, apiPrefix+"/snippets/:id", apiProtected.ThenFunc(app.apiSnippetDelete))
+
+    // the trusted proxies have already been checked by config.validate()
+    trusted, _ := app.config.trustedProxies()
 
     // create a middleware chain using alice 
-    standard := alice.New(app.recoverPanic, app.logRequest, secureHeaders)
+    standard := alice.New(app.recoverPanic, proxyHeaders(trusted), app.logRequest, secureHeaders)
 
     return standard.Then(router)
 }
+
+// redirectRoutes returns the handler for the plain HTTP listener, which
+// permanently redirects every request to the same URL on the HTTPS port
+func (app *application) redirectRoutes() http.Handler {
+    _, port, _ := net.SplitHostPort(app.config.Addr)
+
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Redirect(w, r, httpsURL(r, port), http.StatusPermanentRedirect)
+    })
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "net/http"
+)
+
+// serve runs srv, along with redirect if it is not nil, until ctx is
+// cancelled or one of them fails. Then it shuts them down gracefully: they
+// stop accepting connections, in-flight requests get up to the configured
+// shutdown timeout to complete and we wait for the background goroutines
+// to return. A nil error means the servers were shut down cleanly
+func (app *application) serve(ctx context.Context, srv *http.Server, redirect *http.Server) error {
+    servers := []*http.Server{srv}
+    if redirect != nil {
+        servers = append(servers, redirect)
+    }
+
+    serveErrors := make(chan error, len(servers))
+
+    go func() {
+        // with TLS the certificate comes from srv.TLSConfig.GetCertificate
+        if app.config.TLS.Enabled {
+            app.logger.Info("starting server", "addr", srv.Addr, "tls", true)
+            serveErrors <- srv.ListenAndServeTLS("", "")
+        } else {
+            app.logger.Info("starting server", "addr", srv.Addr, "tls", false)
+            serveErrors <- srv.ListenAndServe()
+        }
+    }()
+
+    if redirect != nil {
+        go func() {
+            app.logger.Info("starting redirect server", "addr", redirect.Addr)
+            serveErrors <- redirect.ListenAndServe()
+        }()
+    }
+
+    // ListenAndServe() only returns early if a server could not start or
+    // failed while running. Either way we shut everything down
+    var err error
+
+    select {
+    case <-ctx.Done():
+    case err = <-serveErrors:
+    }
+
+    app.logger.Info("shutting down server", "addr", srv.Addr)
+
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), app.config.Server.ShutdownTimeout)
+    defer cancel()
+
+    for _, s := range servers {
+        shutdownErr := s.Shutdown(shutdownCtx)
+        if shutdownErr != nil && err == nil {
+            err = shutdownErr
+        }
+    }
+
+    if err != nil && !errors.Is(err, http.ErrServerClosed) {
+        return err
+    }
+
+    app.logger.Info("waiting for background tasks to finish")
+
+    app.wg.Wait()
+
+    app.logger.Info("stopped server", "addr", srv.Addr)
+
+    return nil
+}
@@ -1,38 +1,179 @@
 package main
 
 import (
+    "bytes"
     "html/template"
     "io/fs"
     "path/filepath"
+    "regexp"
+    "strings"
     "time"
+    "unicode/utf8"
 
+    "github.com/j-clemons/snippetbox/internal/diff"
     "github.com/j-clemons/snippetbox/internal/models"
+    "github.com/j-clemons/snippetbox/internal/syntax"
     "github.com/j-clemons/snippetbox/ui"
+
+    "github.com/microcosm-cc/bluemonday"
+    "github.com/yuin/goldmark"
+    "github.com/yuin/goldmark/extension"
+    "github.com/yuin/goldmark/parser"
 )
 
+// revisionDiff holds two revisions of a snippet and the changes between them
+type revisionDiff struct {
+    From  models.Revision
+    To    models.Revision
+    Hunks []diff.Hunk
+}
+
+// Page holds the metadata for one page of a paginated list. PrevURL and
+// NextURL link to the neighbouring pages and are empty if there is no
+// such page. It is rendered by the "pagination" partial
+type Page struct {
+    Size    int
+    PrevURL string
+    NextURL string
+}
+
+// searchData holds the query of a search results page
+type searchData struct {
+    Query string
+}
+
 // define a templateData type to act as a holding structure for any
 // dynamic data that we want to pass to our HTML templates
 type templateData struct {
     CurrentYear     int
     Snippet         models.Snippet
+    Burned          bool
     Snippets        []models.Snippet
+    Revisions       []models.Revision
+    Tokens          []models.Token
+    // NewToken is an API token that has just been created, which is the
+    // only time it can be shown
+    NewToken        string
+    Diff            revisionDiff
+    Search          searchData
+    Page            Page
     Form            any
     Flash           string
     IsAuthenticated bool
+    AuthenticatedID int
     CSRFToken       string
 }
 
 // create a function that returns a formatted time.Time object
 func humanDate(t time.Time) string {
+    // the zero time stands for a snippet that never expires
     if t.IsZero() {
-        return ""
+        return "never"
     }
 
     return t.UTC().Format("02 Jan 2006 at 15:04")
 }
 
+// the number of bytes of context highlight() keeps either side of the
+// first match when cutting a fragment out of a longer text
+const highlightContext = 80
+
+// highlight returns a fragment of text around the first match of any of
+// the words in query, with every match wrapped in a <mark> element. The
+// rest of the text is HTML escaped, so the result is safe to render
+func highlight(text, query string) template.HTML {
+    var terms []string
+    for _, term := range strings.Fields(query) {
+        // full-text search splits words at punctuation, so a query for
+        // "pond" or (pond) finds the word pond. Trim the punctuation so
+        // that it is highlighted too
+        term = strings.Trim(term, `+-~<>*()"`)
+        if term != "" {
+            terms = append(terms, regexp.QuoteMeta(term))
+        }
+    }
+
+    start, end := 0, min(len(text), 2*highlightContext)
+
+    var rx *regexp.Regexp
+    if len(terms) > 0 {
+        rx = regexp.MustCompile("(?i)" + strings.Join(terms, "|"))
+
+        if loc := rx.FindStringIndex(text); loc != nil {
+            start = max(loc[0]-highlightContext, 0)
+            end = min(loc[1]+highlightContext, len(text))
+        }
+    }
+
+    // move the cut points back to the start of a rune so that we never
+    // split a multi-byte character
+    for start > 0 && !utf8.RuneStart(text[start]) {
+        start--
+    }
+    for end < len(text) && !utf8.RuneStart(text[end]) {
+        end--
+    }
+
+    fragment := text[start:end]
+
+    var b strings.Builder
+
+    if start > 0 {
+        b.WriteString("&hellip;")
+    }
+
+    last := 0
+    if rx != nil {
+        for _, loc := range rx.FindAllStringIndex(fragment, -1) {
+            b.WriteString(template.HTMLEscapeString(fragment[last:loc[0]]))
+            b.WriteString("<mark>")
+            b.WriteString(template.HTMLEscapeString(fragment[loc[0]:loc[1]]))
+            b.WriteString("</mark>")
+            last = loc[1]
+        }
+    }
+    b.WriteString(template.HTMLEscapeString(fragment[last:]))
+
+    if end < len(text) {
+        b.WriteString("&hellip;")
+    }
+
+    return template.HTML(b.String())
+}
+
+// md converts markdown to HTML. Raw HTML in the source is never passed
+// through, and headings are given ids so they can be linked to
+var md = goldmark.New(
+    goldmark.WithExtensions(extension.GFM),
+    goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+// markdownPolicy sanitizes the HTML produced from markdown. It is the
+// bluemonday policy for user generated content, which among other things
+// strips scripts and event handlers and adds rel="nofollow" to links
+var markdownPolicy = bluemonday.UGCPolicy()
+
+// markdown renders markdown source as sanitized HTML. Even though goldmark
+// escapes raw HTML we still run the output through the sanitizer, so that
+// things like javascript: URLs in links cannot get through
+func markdown(source string) (template.HTML, error) {
+    var buf bytes.Buffer
+
+    err := md.Convert([]byte(source), &buf)
+    if err != nil {
+        return "", err
+    }
+
+    return template.HTML(markdownPolicy.SanitizeBytes(buf.Bytes())), nil
+}
+
 var functions = template.FuncMap{
-    "humanDate": humanDate,
+    "humanDate":     humanDate,
+    "highlight":     highlight,
+    "syntax":        syntax.HTML,
+    "languages":     func() []syntax.Language { return syntax.Languages },
+    "languageLabel": syntax.Label,
+    "markdown":      markdown,
 }
 
 func newTemplateCache() (map[string]*template.Template, error) {
@@ -1,6 +1,7 @@
 package main
 
 import (
+    "strings"
     "testing"
     "time"
 
@@ -19,9 +20,9 @@ func TestHumanDate(t *testing.T) {
             want: "17 Mar 2023 at 10:15",
         },
         {
-            name: "Empty",
+            name: "Never",
             tm:   time.Time{},
-            want: "",
+            want: "never",
         },
         {
             name: "CET",
@@ -38,3 +39,133 @@ func TestHumanDate(t *testing.T) {
         })
     }
 }
+
+func TestHighlight(t *testing.T) {
+    long := strings.Repeat("a ", 100) + "needle" + strings.Repeat(" b", 100)
+
+    tests := []struct {
+        name  string
+        text  string
+        query string
+        want  string
+    }{
+        {
+            name:  "Single match",
+            text:  "An old silent pond",
+            query: "silent",
+            want:  "An old <mark>silent</mark> pond",
+        },
+        {
+            name:  "Case insensitive",
+            text:  "An old silent pond",
+            query: "POND",
+            want:  "An old silent <mark>pond</mark>",
+        },
+        {
+            name:  "Several terms",
+            text:  "An old silent pond",
+            query: "old pond",
+            want:  "An <mark>old</mark> silent <mark>pond</mark>",
+        },
+        {
+            name:  "Punctuation",
+            text:  "An old silent pond",
+            query: `"old" (pond)`,
+            want:  "An <mark>old</mark> silent <mark>pond</mark>",
+        },
+        {
+            name:  "Escapes HTML",
+            text:  "<script>alert('pond')</script>",
+            query: "pond",
+            want:  "&lt;script&gt;alert(&#39;<mark>pond</mark>&#39;)&lt;/script&gt;",
+        },
+        {
+            name:  "No match",
+            text:  "An old silent pond",
+            query: "frog",
+            want:  "An old silent pond",
+        },
+        {
+            name:  "Fragment",
+            text:  long,
+            query: "needle",
+            want:  "&hellip;" + strings.Repeat("a ", 40) + "<mark>needle</mark>" + strings.Repeat(" b", 40) + "&hellip;",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            assert.Equal(t, string(highlight(tt.text, tt.query)), tt.want)
+        })
+    }
+}
+
+func TestMarkdown(t *testing.T) {
+    tests := []struct {
+        name        string
+        source      string
+        wantContain string
+        wantAbsent  string
+    }{
+        {
+            name:        "Heading anchor",
+            source:      "# Hello World",
+            wantContain: `<h1 id="hello-world">Hello World</h1>`,
+        },
+        {
+            name:        "Nofollow links",
+            source:      "[Go](https://golang.org/)",
+            wantContain: `rel="nofollow"`,
+        },
+        {
+            name:       "Script tag",
+            source:     "<script>alert(1)</script>",
+            wantAbsent: "<script",
+        },
+        {
+            name:       "Javascript URL",
+            source:     "[click me](javascript:alert(1))",
+            wantAbsent: "javascript:",
+        },
+        {
+            name:       "Event handler",
+            source:     `<img src="x" onerror="alert(1)">`,
+            wantAbsent: "onerror",
+        },
+        {
+            name:       "Attribute breakout",
+            source:     `![x](x" onerror="alert(1))`,
+            wantAbsent: `" onerror="`,
+        },
+        {
+            name:       "Inline style",
+            source:     `<p style="position:fixed;top:0">overlay</p>`,
+            wantAbsent: "style=",
+        },
+        {
+            name:       "Iframe",
+            source:     `<iframe src="https://example.com"></iframe>`,
+            wantAbsent: "<iframe",
+        },
+        {
+            name:       "Autolink",
+            source:     "<javascript:alert(1)>",
+            wantAbsent: "href=\"javascript:",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            html, err := markdown(tt.source)
+            assert.NilError(t, err)
+
+            if tt.wantContain != "" {
+                assert.StringContains(t, string(html), tt.wantContain)
+            }
+
+            if tt.wantAbsent != "" && strings.Contains(string(html), tt.wantAbsent) {
+                t.Errorf("got: %q; expected not to contain: %q", html, tt.wantAbsent)
+            }
+        })
+    }
+}
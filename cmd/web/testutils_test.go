@@ -10,6 +10,7 @@ import (
     "net/http/httptest"
     "net/url"
     "regexp"
+    "strings"
     "testing"
     "time"
 
@@ -43,12 +44,15 @@ func newTestApplication(t *testing.T) *application {
     sessionManager.Cookie.Secure = true
 
     return &application{
+        config:         defaultConfig(),
         logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
         snippets:       &mocks.SnippetModel{},
         users:          &mocks.UserModel{},
+        tokens:         &mocks.TokenModel{},
         templateCache:  templateCache,
         formDecoder:    formDecoder,
         sessionManager: sessionManager,
+        unlockThrottle: newThrottle(unlockAttempts, unlockWindow),
     }
 }
 
@@ -104,3 +108,47 @@ func (ts *testServer) postForm(t *testing.T, urlPath string, form url.Values) (i
 
     return rs.StatusCode, rs.Header, string(body)
 }
+
+// login signs in as the mock user alice@example.com, so that any later
+// requests made with the test server's client are authenticated
+func (ts *testServer) login(t *testing.T) {
+    _, _, body := ts.get(t, "/user/login/")
+    csrfToken := extractCSRFToken(t, body)
+
+    form := url.Values{}
+    form.Add("email", "alice@example.com")
+    form.Add("password", "pa$$word")
+    form.Add("csrf_token", csrfToken)
+
+    code, _, _ := ts.postForm(t, "/user/login/", form)
+    if code != http.StatusSeeOther {
+        t.Fatalf("login failed with status %d", code)
+    }
+}
+
+// do sends a request with any method, body and headers, as the API tests
+// need, and returns the response like get and postForm do
+func (ts *testServer) do(t *testing.T, method, urlPath string, body string, headers http.Header) (int, http.Header, string) {
+    req, err := http.NewRequest(method, ts.URL+urlPath, strings.NewReader(body))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for key, values := range headers {
+        req.Header[key] = values
+    }
+
+    rs, err := ts.Client().Do(req)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    defer rs.Body.Close()
+    b, err := io.ReadAll(rs.Body)
+    if err != nil {
+        t.Fatal(err)
+    }
+    b = bytes.TrimSpace(b)
+
+    return rs.StatusCode, rs.Header, string(b)
+}
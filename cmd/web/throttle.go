@@ -0,0 +1,79 @@
+package main
+
+import (
+    "sync"
+    "time"
+)
+
+// throttle counts attempts per key, such as a snippet and the address of
+// the client guessing its passphrase, and refuses further attempts once
+// there have been limit of them within window. A successful attempt
+// resets the count, so only failures add up
+type throttle struct {
+    mu       sync.Mutex
+    limit    int
+    window   time.Duration
+    attempts map[string][]time.Time
+}
+
+func newThrottle(limit int, window time.Duration) *throttle {
+    return &throttle{
+        limit:    limit,
+        window:   window,
+        attempts: make(map[string][]time.Time),
+    }
+}
+
+// allow reports whether another attempt may be made for the key, and if
+// so counts it. Checking and counting happen under the same lock, so that
+// requests racing each other cannot all get in under the limit
+func (t *throttle) allow(key string) bool {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    now := time.Now()
+
+    times := t.recent(key, now)
+    if len(times) >= t.limit {
+        return false
+    }
+
+    t.attempts[key] = append(times, now)
+
+    // every key we have seen stays in the map until it is looked at
+    // again, so clear out the stale ones before the map grows too large
+    if len(t.attempts) > 10000 {
+        for k := range t.attempts {
+            t.recent(k, now)
+        }
+    }
+
+    return true
+}
+
+// reset forgets the attempts for the key, after a success
+func (t *throttle) reset(key string) {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+
+    delete(t.attempts, key)
+}
+
+// recent drops the attempts for the key that are older than the window
+// and returns the rest. The caller must hold the lock
+func (t *throttle) recent(key string, now time.Time) []time.Time {
+    times := t.attempts[key]
+
+    i := 0
+    for i < len(times) && now.Sub(times[i]) >= t.window {
+        i++
+    }
+
+    if i == len(times) {
+        delete(t.attempts, key)
+        return nil
+    }
+
+    t.attempts[key] = times[i:]
+    return times[i:]
+}
@@ -0,0 +1,173 @@
+package diff
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Op describes what happened to a single line between two texts
+type Op int
+
+const (
+    Equal Op = iota
+    Insert
+    Delete
+)
+
+// Line is one line of a diff. OldNum and NewNum are the 1-based line
+// numbers in the old and new text, and are 0 when the line does not
+// exist on that side
+type Line struct {
+    Op     Op
+    Text   string
+    OldNum int
+    NewNum int
+}
+
+// Prefix returns the unified diff marker for the line
+func (l Line) Prefix() string {
+    switch l.Op {
+    case Insert:
+        return "+"
+    case Delete:
+        return "-"
+    default:
+        return " "
+    }
+}
+
+// Hunk is a group of changed lines surrounded by some unchanged context
+type Hunk struct {
+    OldStart int
+    OldLines int
+    NewStart int
+    NewLines int
+    Lines    []Line
+}
+
+// Header returns the "@@ -a,b +c,d @@" range line for the hunk
+func (h Hunk) Header() string {
+    return fmt.Sprintf("@@ -%d,%d +%d,%d @@", h.OldStart, h.OldLines, h.NewStart, h.NewLines)
+}
+
+// Lines computes a line based diff of a and b using the longest common
+// subsequence of their lines. Every line of both texts appears in the
+// result exactly once
+func Lines(a, b string) []Line {
+    x := splitLines(a)
+    y := splitLines(b)
+
+    // lcs[i][j] holds the length of the longest common subsequence of
+    // x[i:] and y[j:]
+    lcs := make([][]int, len(x)+1)
+    for i := range lcs {
+        lcs[i] = make([]int, len(y)+1)
+    }
+    for i := len(x) - 1; i >= 0; i-- {
+        for j := len(y) - 1; j >= 0; j-- {
+            if x[i] == y[j] {
+                lcs[i][j] = lcs[i+1][j+1] + 1
+            } else {
+                lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+            }
+        }
+    }
+
+    var lines []Line
+    i, j := 0, 0
+    for i < len(x) || j < len(y) {
+        switch {
+        case i < len(x) && j < len(y) && x[i] == y[j]:
+            lines = append(lines, Line{Op: Equal, Text: x[i], OldNum: i + 1, NewNum: j + 1})
+            i++
+            j++
+        case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+            // prefer deletions so that removed lines are listed before
+            // the lines that replace them
+            lines = append(lines, Line{Op: Delete, Text: x[i], OldNum: i + 1})
+            i++
+        default:
+            lines = append(lines, Line{Op: Insert, Text: y[j], NewNum: j + 1})
+            j++
+        }
+    }
+
+    return lines
+}
+
+// Unified groups the diff of a and b into hunks, keeping context lines of
+// unchanged text around each change, like `diff -u`. Identical texts
+// produce no hunks
+func Unified(a, b string, context int) []Hunk {
+    lines := Lines(a, b)
+
+    var hunks []Hunk
+    var current *Hunk
+    // lastChange is the index in lines of the last changed line added to
+    // the current hunk
+    lastChange := -1
+
+    for i, line := range lines {
+        if line.Op == Equal {
+            continue
+        }
+
+        start := max(i-context, 0)
+        if current != nil && start <= lastChange+context+1 {
+            // close enough to the previous change to share a hunk, so
+            // pull in everything in between
+            current.Lines = append(current.Lines, lines[lastChange+1:i+1]...)
+        } else {
+            if current != nil {
+                end := min(lastChange+context+1, len(lines))
+                current.Lines = append(current.Lines, lines[lastChange+1:end]...)
+                hunks = append(hunks, *current)
+            }
+            current = &Hunk{}
+            current.Lines = append(current.Lines, lines[start:i+1]...)
+        }
+        lastChange = i
+    }
+
+    if current != nil {
+        end := min(lastChange+context+1, len(lines))
+        current.Lines = append(current.Lines, lines[lastChange+1:end]...)
+        hunks = append(hunks, *current)
+    }
+
+    for i := range hunks {
+        hunks[i].setRanges()
+    }
+
+    return hunks
+}
+
+// setRanges fills in the start and length of the hunk on both sides
+func (h *Hunk) setRanges() {
+    for _, line := range h.Lines {
+        if line.Op != Insert {
+            if h.OldStart == 0 {
+                h.OldStart = line.OldNum
+            }
+            h.OldLines++
+        }
+        if line.Op != Delete {
+            if h.NewStart == 0 {
+                h.NewStart = line.NewNum
+            }
+            h.NewLines++
+        }
+    }
+}
+
+// splitLines splits s into lines, normalising Windows line endings and
+// ignoring a single trailing newline
+func splitLines(s string) []string {
+    s = strings.ReplaceAll(s, "\r\n", "\n")
+    s = strings.TrimSuffix(s, "\n")
+    if s == "" {
+        return nil
+    }
+
+    return strings.Split(s, "\n")
+}
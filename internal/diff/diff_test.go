@@ -0,0 +1,73 @@
+package diff
+
+import (
+    "testing"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestLines(t *testing.T) {
+    lines := Lines("a\nb\nc", "a\nx\nc\nd")
+
+    want := []Line{
+        {Op: Equal, Text: "a", OldNum: 1, NewNum: 1},
+        {Op: Delete, Text: "b", OldNum: 2},
+        {Op: Insert, Text: "x", NewNum: 2},
+        {Op: Equal, Text: "c", OldNum: 3, NewNum: 3},
+        {Op: Insert, Text: "d", NewNum: 4},
+    }
+
+    assert.Equal(t, len(lines), len(want))
+    for i := range want {
+        assert.Equal(t, lines[i], want[i])
+    }
+}
+
+func TestUnified(t *testing.T) {
+    tests := []struct {
+        name        string
+        a           string
+        b           string
+        wantHunks   int
+        wantHeaders []string
+    }{
+        {
+            name:      "Identical",
+            a:         "a\nb\nc",
+            b:         "a\nb\nc\n",
+            wantHunks: 0,
+        },
+        {
+            name:        "Single change",
+            a:           "1\n2\n3\n4\n5\n6\n7\n8\n9",
+            b:           "1\n2\n3\n4\nfive\n6\n7\n8\n9",
+            wantHunks:   1,
+            wantHeaders: []string{"@@ -2,7 +2,7 @@"},
+        },
+        {
+            name:        "Distant changes",
+            a:           "1\n2\n3\n4\n5\n6\n7\n8\n9\n10",
+            b:           "one\n2\n3\n4\n5\n6\n7\n8\n9\nten",
+            wantHunks:   2,
+            wantHeaders: []string{"@@ -1,4 +1,4 @@", "@@ -7,4 +7,4 @@"},
+        },
+        {
+            name:        "From empty",
+            a:           "",
+            b:           "a\nb",
+            wantHunks:   1,
+            wantHeaders: []string{"@@ -0,0 +1,2 @@"},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            hunks := Unified(tt.a, tt.b, 3)
+
+            assert.Equal(t, len(hunks), tt.wantHunks)
+            for i, header := range tt.wantHeaders {
+                assert.Equal(t, hunks[i].Header(), header)
+            }
+        })
+    }
+}
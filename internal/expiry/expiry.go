@@ -0,0 +1,105 @@
+// Package expiry works out when a snippet expires from the durations
+// and dates people enter for it, such as "36h", "2w" or "2025-01-31".
+package expiry
+
+import (
+    "errors"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// Never is the value people use for a snippet that should not expire.
+// It is represented by the zero time.Time
+const Never = "never"
+
+// the shortest and longest time a snippet can be kept for
+const (
+    Min = time.Hour
+    Max = 10 * 365 * 24 * time.Hour
+)
+
+// DateLayout is the layout of the dates sent by an HTML date input
+const DateLayout = "2006-01-02"
+
+var (
+    ErrInvalid = errors.New("expiry: invalid duration")
+    ErrTooSoon = errors.New("expiry: too soon")
+    ErrTooLate = errors.New("expiry: too late")
+)
+
+// the units a duration may be given in. Months and years are taken to
+// be 30 and 365 days, which is close enough for expiring snippets
+var units = map[string]time.Duration{
+    "h":  time.Hour,
+    "d":  24 * time.Hour,
+    "w":  7 * 24 * time.Hour,
+    "mo": 30 * 24 * time.Hour,
+    "y":  365 * 24 * time.Hour,
+}
+
+// ParseDuration parses a whole number followed by a unit of h, d, w, mo
+// or y, such as "36h" or "2y"
+func ParseDuration(s string) (time.Duration, error) {
+    s = strings.ToLower(strings.TrimSpace(s))
+
+    i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+    if i < 1 {
+        return 0, ErrInvalid
+    }
+
+    n, err := strconv.Atoi(s[:i])
+    if err != nil {
+        return 0, ErrInvalid
+    }
+
+    unit, ok := units[s[i:]]
+    if !ok {
+        return 0, ErrInvalid
+    }
+
+    // anything that would overflow is far beyond Max anyway
+    if n > int(Max/unit)+1 {
+        return 0, ErrTooLate
+    }
+
+    return time.Duration(n) * unit, nil
+}
+
+// In returns the time a snippet created at now expires if it is kept for
+// the duration s, or the zero time if s is Never
+func In(s string, now time.Time) (time.Time, error) {
+    if strings.EqualFold(strings.TrimSpace(s), Never) {
+        return time.Time{}, nil
+    }
+
+    d, err := ParseDuration(s)
+    if err != nil {
+        return time.Time{}, err
+    }
+
+    return check(now.Add(d), now)
+}
+
+// On returns the time a snippet expires if it is kept until the end of
+// the date s, given in DateLayout and taken to be in UTC
+func On(s string, now time.Time) (time.Time, error) {
+    date, err := time.Parse(DateLayout, strings.TrimSpace(s))
+    if err != nil {
+        return time.Time{}, ErrInvalid
+    }
+
+    return check(date.AddDate(0, 0, 1), now)
+}
+
+// check makes sure that an expiry time is between Min and Max from now
+func check(t time.Time, now time.Time) (time.Time, error) {
+    switch {
+    case t.Sub(now) < Min:
+        return time.Time{}, ErrTooSoon
+    case t.Sub(now) > Max:
+        return time.Time{}, ErrTooLate
+    }
+
+    return t.UTC().Truncate(time.Second), nil
+}
@@ -0,0 +1,100 @@
+package expiry
+
+import (
+    "errors"
+    "testing"
+    "time"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestIn(t *testing.T) {
+    now := time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC)
+
+    tests := []struct {
+        name    string
+        value   string
+        want    time.Time
+        wantErr error
+    }{
+        {
+            name:  "Hours",
+            value: "36h",
+            want:  time.Date(2024, 3, 18, 22, 15, 0, 0, time.UTC),
+        },
+        {
+            name:  "Weeks",
+            value: "2w",
+            want:  time.Date(2024, 3, 31, 10, 15, 0, 0, time.UTC),
+        },
+        {
+            name:  "Years",
+            value: "1y",
+            want:  time.Date(2025, 3, 17, 10, 15, 0, 0, time.UTC),
+        },
+        {
+            name:  "Never",
+            value: "Never",
+            want:  time.Time{},
+        },
+        {
+            name:    "Zero",
+            value:   "0h",
+            wantErr: ErrTooSoon,
+        },
+        {
+            name:    "Too long",
+            value:   "11y",
+            wantErr: ErrTooLate,
+        },
+        {
+            name:    "Overflow",
+            value:   "99999999999y",
+            wantErr: ErrTooLate,
+        },
+        {
+            name:    "No unit",
+            value:   "36",
+            wantErr: ErrInvalid,
+        },
+        {
+            name:    "Unknown unit",
+            value:   "3fortnights",
+            wantErr: ErrInvalid,
+        },
+        {
+            name:    "Negative",
+            value:   "-1d",
+            wantErr: ErrInvalid,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := In(tt.value, now)
+
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("got error %v; want %v", err, tt.wantErr)
+            }
+            assert.Equal(t, got, tt.want)
+        })
+    }
+}
+
+func TestOn(t *testing.T) {
+    now := time.Date(2024, 3, 17, 10, 15, 0, 0, time.UTC)
+
+    got, err := On("2024-12-31", now)
+    assert.NilError(t, err)
+    assert.Equal(t, got, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+    _, err = On("2024-03-16", now)
+    if !errors.Is(err, ErrTooSoon) {
+        t.Errorf("got error %v; want %v", err, ErrTooSoon)
+    }
+
+    _, err = On("31/12/2024", now)
+    if !errors.Is(err, ErrInvalid) {
+        t.Errorf("got error %v; want %v", err, ErrInvalid)
+    }
+}
@@ -0,0 +1,495 @@
+// Package migrations keeps the database schema up to date. Each change to
+// the schema is a numbered pair of SQL files, such as 0002_create_snippets.up.sql
+// and 0002_create_snippets.down.sql, embedded in the binary. Each database
+// has its own directory of migrations, named after its dialect. The versions
+// that have been applied are recorded in the schema_migrations table.
+//
+// The first Baseline migrations create the schema that snippetbox had
+// before it managed its schema with migrations, so that a database set up
+// by hand back then can be adopted and brought up to date.
+package migrations
+
+import (
+    "database/sql"
+    "embed"
+    "errors"
+    "fmt"
+    "io/fs"
+    "regexp"
+    "slices"
+    "strconv"
+    "strings"
+    "time"
+)
+
+//go:embed "mysql" "sqlite" "postgres"
+var Files embed.FS
+
+// the dialects there are migrations for, which are also the names of their
+// directories in Files
+const (
+    MySQL    = "mysql"
+    SQLite   = "sqlite"
+    Postgres = "postgres"
+)
+
+// Baseline is the version of the newest migration that creates part of the
+// schema from before migrations were used. A database that already has
+// the tables created by these migrations, but no record of any migration,
+// is taken to be at this version
+const Baseline = 3
+
+// the tables created by the Baseline migrations
+var baselineTables = []string{"users", "snippets", "sessions"}
+
+// ErrOutdated is returned by Check when the database is missing
+// migrations that the code depends on
+var ErrOutdated = errors.New("migrations: database schema is out of date")
+
+// Migration is a single versioned change to the schema
+type Migration struct {
+    Version int
+    Name    string
+    Up      string
+    Down    string
+}
+
+// Status is a migration along with when it was applied, which is the zero
+// time if it is still pending
+type Status struct {
+    Migration
+    Applied time.Time
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+    DB         *sql.DB
+    // Dialect decides how the schema_migrations table is queried. Blank
+    // means MySQL
+    Dialect    string
+    Migrations []Migration
+}
+
+// the name of a migration file, such as 0002_create_snippets.up.sql
+var filenameRX = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// New returns a Migrator for the migrations embedded in the binary for the
+// given dialect
+func New(db *sql.DB, dialect string) (*Migrator, error) {
+    if dialect != MySQL && dialect != SQLite && dialect != Postgres {
+        return nil, fmt.Errorf("migrations: unknown dialect %q", dialect)
+    }
+
+    dir, err := fs.Sub(Files, dialect)
+    if err != nil {
+        return nil, err
+    }
+
+    migrations, err := Load(dir)
+    if err != nil {
+        return nil, err
+    }
+
+    return &Migrator{DB: db, Dialect: dialect, Migrations: migrations}, nil
+}
+
+// Load reads the migrations in the top level of fsys, ordered by version.
+// Every migration must have both an up and a down file, and versions must
+// be unique
+func Load(fsys fs.FS) ([]Migration, error) {
+    entries, err := fs.ReadDir(fsys, ".")
+    if err != nil {
+        return nil, err
+    }
+
+    byVersion := map[int]*Migration{}
+
+    for _, entry := range entries {
+        matches := filenameRX.FindStringSubmatch(entry.Name())
+        if matches == nil {
+            continue
+        }
+
+        version, err := strconv.Atoi(matches[1])
+        if err != nil || version < 1 {
+            return nil, fmt.Errorf("migrations: invalid version in %s", entry.Name())
+        }
+
+        m, ok := byVersion[version]
+        if !ok {
+            m = &Migration{Version: version, Name: matches[2]}
+            byVersion[version] = m
+        } else if m.Name != matches[2] {
+            return nil, fmt.Errorf("migrations: version %d is used by both %s and %s", version, m.Name, matches[2])
+        }
+
+        script, err := fs.ReadFile(fsys, entry.Name())
+        if err != nil {
+            return nil, err
+        }
+
+        if matches[3] == "up" {
+            m.Up = string(script)
+        } else {
+            m.Down = string(script)
+        }
+    }
+
+    var migrations []Migration
+
+    for _, m := range byVersion {
+        if m.Up == "" || m.Down == "" {
+            return nil, fmt.Errorf("migrations: version %d (%s) needs both an up and a down file", m.Version, m.Name)
+        }
+        migrations = append(migrations, *m)
+    }
+
+    slices.SortFunc(migrations, func(a, b Migration) int {
+        return a.Version - b.Version
+    })
+
+    return migrations, nil
+}
+
+// Latest returns the version of the newest migration, which is the schema
+// version the code expects
+func (m *Migrator) Latest() int {
+    if len(m.Migrations) == 0 {
+        return 0
+    }
+
+    return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Version returns the version of the newest migration applied to the
+// database, or 0 if none have been
+func (m *Migrator) Version() (int, error) {
+    err := m.createTable()
+    if err != nil {
+        return 0, err
+    }
+
+    var version int
+
+    err = m.DB.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+    if err != nil {
+        return 0, err
+    }
+
+    return version, nil
+}
+
+// Check returns ErrOutdated, wrapped with the versions involved, if the
+// database has not had every migration applied
+func (m *Migrator) Check() error {
+    applied, err := m.applied()
+    if err != nil {
+        return err
+    }
+
+    for _, migration := range m.Migrations {
+        if _, ok := applied[migration.Version]; !ok {
+            return fmt.Errorf("%w: version %d (%s) has not been applied", ErrOutdated, migration.Version, migration.Name)
+        }
+    }
+
+    return nil
+}
+
+// Status lists every migration and whether it has been applied
+func (m *Migrator) Status() ([]Status, error) {
+    applied, err := m.applied()
+    if err != nil {
+        return nil, err
+    }
+
+    var statuses []Status
+
+    for _, migration := range m.Migrations {
+        statuses = append(statuses, Status{Migration: migration, Applied: applied[migration.Version]})
+    }
+
+    return statuses, nil
+}
+
+// Up applies every pending migration in order, and returns the ones that
+// were applied. A database with the baseline schema and no migrations
+// recorded is adopted first, so the Baseline migrations are not run
+func (m *Migrator) Up() ([]Migration, error) {
+    applied, err := m.applied()
+    if err != nil {
+        return nil, err
+    }
+
+    if len(applied) == 0 {
+        applied, err = m.adopt()
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    var done []Migration
+
+    for _, migration := range m.Migrations {
+        if _, ok := applied[migration.Version]; ok {
+            continue
+        }
+
+        // the time comes from Go rather than SQL, since every database
+        // has its own name for the current time
+        err := m.run(migration.Up, `INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)`,
+            migration.Version, migration.Name, time.Now().UTC().Truncate(time.Second))
+        if err != nil {
+            return done, fmt.Errorf("migrations: applying %d (%s): %w", migration.Version, migration.Name, err)
+        }
+
+        done = append(done, migration)
+    }
+
+    return done, nil
+}
+
+// Down reverts the newest steps applied migrations, newest first, and
+// returns the ones that were reverted
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+    applied, err := m.applied()
+    if err != nil {
+        return nil, err
+    }
+
+    var done []Migration
+
+    for i := len(m.Migrations) - 1; i >= 0 && len(done) < steps; i-- {
+        migration := m.Migrations[i]
+        if _, ok := applied[migration.Version]; !ok {
+            continue
+        }
+
+        err := m.run(migration.Down, `DELETE FROM schema_migrations WHERE version = ?`, migration.Version)
+        if err != nil {
+            return done, fmt.Errorf("migrations: reverting %d (%s): %w", migration.Version, migration.Name, err)
+        }
+
+        done = append(done, migration)
+    }
+
+    return done, nil
+}
+
+// adopt records the Baseline migrations as applied if the database already
+// has every table they create, and returns the versions that are now
+// applied. A database with only some of those tables is an error, since
+// it is not clear what state it is in
+func (m *Migrator) adopt() (map[int]time.Time, error) {
+    var found, missing []string
+
+    for _, table := range baselineTables {
+        ok, err := m.tableExists(table)
+        if err != nil {
+            return nil, err
+        }
+
+        if ok {
+            found = append(found, table)
+        } else {
+            missing = append(missing, table)
+        }
+    }
+
+    if len(found) == 0 {
+        return map[int]time.Time{}, nil
+    }
+    if len(missing) > 0 {
+        return nil, fmt.Errorf("migrations: cannot adopt a database that has the %s tables but not %s",
+            strings.Join(found, ", "), strings.Join(missing, ", "))
+    }
+
+    applied := map[int]time.Time{}
+    now := time.Now().UTC().Truncate(time.Second)
+
+    for _, migration := range m.Migrations {
+        if migration.Version > Baseline {
+            break
+        }
+
+        _, err := m.DB.Exec(m.bind(`INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)`),
+            migration.Version, migration.Name, now)
+        if err != nil {
+            return nil, err
+        }
+
+        applied[migration.Version] = now
+    }
+
+    return applied, nil
+}
+
+// tableExists reports whether the database has a table with the given name
+func (m *Migrator) tableExists(table string) (bool, error) {
+    query := `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?`
+    switch m.Dialect {
+    case SQLite:
+        query = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+    case Postgres:
+        query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+    }
+
+    var n int
+
+    err := m.DB.QueryRow(m.bind(query), table).Scan(&n)
+    if err != nil {
+        return false, err
+    }
+
+    return n > 0, nil
+}
+
+// createTable creates the schema_migrations table if it does not exist
+func (m *Migrator) createTable() error {
+    // PostgreSQL has no DATETIME type
+    appliedType := "DATETIME"
+    if m.Dialect == Postgres {
+        appliedType = "TIMESTAMP"
+    }
+
+    _, err := m.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied ` + appliedType + ` NOT NULL
+)`)
+
+    return err
+}
+
+// bind rewrites the ? placeholders of a query into the $1, $2, ... form
+// that PostgreSQL uses. Queries for other dialects are left alone
+func (m *Migrator) bind(query string) string {
+    if m.Dialect != Postgres {
+        return query
+    }
+
+    var b strings.Builder
+    n := 0
+
+    for _, r := range query {
+        if r == '?' {
+            n++
+            fmt.Fprintf(&b, "$%d", n)
+            continue
+        }
+        b.WriteRune(r)
+    }
+
+    return b.String()
+}
+
+// applied returns the versions that have been applied, and when
+func (m *Migrator) applied() (map[int]time.Time, error) {
+    err := m.createTable()
+    if err != nil {
+        return nil, err
+    }
+
+    rows, err := m.DB.Query(`SELECT version, applied FROM schema_migrations`)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    applied := map[int]time.Time{}
+
+    for rows.Next() {
+        var version int
+        var at time.Time
+
+        err := rows.Scan(&version, &at)
+        if err != nil {
+            return nil, err
+        }
+
+        applied[version] = at
+    }
+
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return applied, nil
+}
+
+// run executes each statement of a migration script in turn, and then the
+// record statement, which updates schema_migrations with args. SQLite and
+// PostgreSQL can roll back changes to the schema, so there it all happens
+// in one transaction and a migration that fails part way leaves nothing
+// behind. MySQL commits every change to the schema as soon as it is made,
+// so a transaction would not help there
+func (m *Migrator) run(script string, record string, args ...any) error {
+    var db interface {
+        Exec(query string, args ...any) (sql.Result, error)
+    } = m.DB
+
+    var tx *sql.Tx
+
+    if m.Dialect == SQLite || m.Dialect == Postgres {
+        var err error
+
+        tx, err = m.DB.Begin()
+        if err != nil {
+            return err
+        }
+        // rollback is a no-op once the transaction has been committed
+        defer tx.Rollback()
+
+        db = tx
+    }
+
+    // statements end with a semicolon at the end of a line, so that the
+    // script can be run without enabling multiple statements on the
+    // connection
+    for _, stmt := range Statements(script) {
+        _, err := db.Exec(stmt)
+        if err != nil {
+            return err
+        }
+    }
+
+    _, err := db.Exec(m.bind(record), args...)
+    if err != nil {
+        return err
+    }
+
+    if tx != nil {
+        return tx.Commit()
+    }
+
+    return nil
+}
+
+// Statements splits a script into statements, each of which ends with a
+// semicolon at the end of a line. Blank lines and lines starting with --
+// are left out
+func Statements(script string) []string {
+    var statements []string
+    var b strings.Builder
+
+    for _, line := range strings.Split(script, "\n") {
+        trimmed := strings.TrimSpace(line)
+        if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+            continue
+        }
+
+        b.WriteString(line)
+        b.WriteString("\n")
+
+        if strings.HasSuffix(trimmed, ";") {
+            statements = append(statements, strings.TrimSuffix(strings.TrimSpace(b.String()), ";"))
+            b.Reset()
+        }
+    }
+
+    if rest := strings.TrimSpace(b.String()); rest != "" {
+        statements = append(statements, rest)
+    }
+
+    return statements
+}
@@ -0,0 +1,262 @@
+package migrations
+
+import (
+    "database/sql"
+    "regexp"
+    "testing"
+    "testing/fstest"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+
+    _ "github.com/mattn/go-sqlite3"
+)
+
+func TestLoad(t *testing.T) {
+    fsys := fstest.MapFS{
+        "0002_add_bar.up.sql":      {Data: []byte("ALTER TABLE foo ADD bar INTEGER;")},
+        "0002_add_bar.down.sql":    {Data: []byte("ALTER TABLE foo DROP bar;")},
+        "0001_create_foo.up.sql":   {Data: []byte("CREATE TABLE foo (id INTEGER);")},
+        "0001_create_foo.down.sql": {Data: []byte("DROP TABLE foo;")},
+        "README":                   {Data: []byte("not a migration")},
+    }
+
+    migrations, err := Load(fsys)
+    assert.NilError(t, err)
+
+    assert.Equal(t, len(migrations), 2)
+    assert.Equal(t, migrations[0].Version, 1)
+    assert.Equal(t, migrations[0].Name, "create_foo")
+    assert.Equal(t, migrations[1].Version, 2)
+    assert.Equal(t, migrations[1].Down, "ALTER TABLE foo DROP bar;")
+
+    m := Migrator{Migrations: migrations}
+    assert.Equal(t, m.Latest(), 2)
+}
+
+func TestLoadInvalid(t *testing.T) {
+    tests := []struct {
+        name string
+        fsys fstest.MapFS
+    }{
+        {
+            name: "Missing down",
+            fsys: fstest.MapFS{
+                "0001_create_foo.up.sql": {Data: []byte("CREATE TABLE foo (id INTEGER);")},
+            },
+        },
+        {
+            name: "Duplicate version",
+            fsys: fstest.MapFS{
+                "0001_create_foo.up.sql":   {Data: []byte("CREATE TABLE foo (id INTEGER);")},
+                "0001_create_foo.down.sql": {Data: []byte("DROP TABLE foo;")},
+                "0001_create_bar.up.sql":   {Data: []byte("CREATE TABLE bar (id INTEGER);")},
+                "0001_create_bar.down.sql": {Data: []byte("DROP TABLE bar;")},
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := Load(tt.fsys)
+            if err == nil {
+                t.Errorf("expected an error")
+            }
+        })
+    }
+}
+
+func TestEmbedded(t *testing.T) {
+    mysql, err := New(nil, MySQL)
+    assert.NilError(t, err)
+
+    sqlite, err := New(nil, SQLite)
+    assert.NilError(t, err)
+
+    postgres, err := New(nil, Postgres)
+    assert.NilError(t, err)
+
+    // versions start at 1 and have no gaps
+    for i, migration := range mysql.Migrations {
+        assert.Equal(t, migration.Version, i+1)
+    }
+
+    // every dialect has the same migrations, so that the schema version
+    // means the same thing whichever database is used
+    for _, other := range []*Migrator{sqlite, postgres} {
+        assert.Equal(t, len(other.Migrations), len(mysql.Migrations))
+        for i, migration := range other.Migrations {
+            assert.Equal(t, migration.Name, mysql.Migrations[i].Name)
+        }
+    }
+
+    _, err = New(nil, "oracle")
+    if err == nil {
+        t.Errorf("expected an error for an unknown dialect")
+    }
+}
+
+func TestStatements(t *testing.T) {
+    script := `-- the users table
+CREATE TABLE users (
+    id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL
+);
+
+ALTER TABLE users ADD CONSTRAINT users_uc_name UNIQUE (name);
+`
+
+    statements := Statements(script)
+
+    assert.Equal(t, len(statements), 2)
+    assert.Equal(t, statements[0], "CREATE TABLE users (\n    id INTEGER NOT NULL,\n    name VARCHAR(255) NOT NULL\n)")
+    assert.Equal(t, statements[1], "ALTER TABLE users ADD CONSTRAINT users_uc_name UNIQUE (name)")
+}
+
+func TestBind(t *testing.T) {
+    query := `INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)`
+
+    m := Migrator{Dialect: MySQL}
+    assert.Equal(t, m.bind(query), query)
+
+    m = Migrator{Dialect: Postgres}
+    assert.Equal(t, m.bind(query), `INSERT INTO schema_migrations (version, name, applied) VALUES ($1, $2, $3)`)
+}
+
+// newSQLiteMigrator returns a Migrator for an empty in-memory SQLite
+// database, which needs no database server
+func newSQLiteMigrator(t *testing.T) *Migrator {
+    db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // every connection to :memory: gets a database of its own, so keep to
+    // just one
+    db.SetMaxOpenConns(1)
+    t.Cleanup(func() { db.Close() })
+
+    m, err := New(db, SQLite)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return m
+}
+
+func TestUpDown(t *testing.T) {
+    m := newSQLiteMigrator(t)
+
+    done, err := m.Up()
+    assert.NilError(t, err)
+    assert.Equal(t, len(done), len(m.Migrations))
+    assert.NilError(t, m.Check())
+
+    // every down migration has to undo its up migration well enough for
+    // the up migration to run again
+    done, err = m.Down(len(m.Migrations))
+    assert.NilError(t, err)
+    assert.Equal(t, len(done), len(m.Migrations))
+
+    version, err := m.Version()
+    assert.NilError(t, err)
+    assert.Equal(t, version, 0)
+
+    done, err = m.Up()
+    assert.NilError(t, err)
+    assert.Equal(t, len(done), len(m.Migrations))
+}
+
+func TestUpRollsBack(t *testing.T) {
+    m := newSQLiteMigrator(t)
+    m.Migrations = []Migration{
+        {Version: 1, Name: "create_foo", Up: "CREATE TABLE foo (id INTEGER);", Down: "DROP TABLE foo;"},
+        {Version: 2, Name: "broken", Up: "CREATE TABLE bar (id INTEGER);\nINSERT INTO missing VALUES (1);", Down: "DROP TABLE bar;"},
+    }
+
+    done, err := m.Up()
+    if err == nil {
+        t.Fatal("expected an error")
+    }
+    assert.Equal(t, len(done), 1)
+
+    // the table created before the failing statement is rolled back along
+    // with it, so the migration can be fixed and run again
+    exists, err := m.tableExists("bar")
+    assert.NilError(t, err)
+    assert.Equal(t, exists, false)
+
+    version, err := m.Version()
+    assert.NilError(t, err)
+    assert.Equal(t, version, 1)
+}
+
+func TestUpAdoptsBaseline(t *testing.T) {
+    m := newSQLiteMigrator(t)
+
+    // set up the database by hand, the way it was done before there were
+    // migrations, with snippets that belong to nobody
+    for _, migration := range m.Migrations[:Baseline] {
+        for _, stmt := range Statements(migration.Up) {
+            _, err := m.DB.Exec(stmt)
+            assert.NilError(t, err)
+        }
+    }
+
+    for _, title := range []string{"An old silent pond", "Over the wintry forest"} {
+        _, err := m.DB.Exec(`INSERT INTO snippets (title, content, created, expires) VALUES (?, 'Haiku', '2022-01-01 10:00:00', '2099-01-01 10:00:00')`, title)
+        assert.NilError(t, err)
+    }
+
+    err := m.Check()
+    if err == nil {
+        t.Fatal("expected the database to be out of date")
+    }
+
+    done, err := m.Up()
+    assert.NilError(t, err)
+    assert.Equal(t, len(done), len(m.Migrations)-Baseline)
+    assert.Equal(t, done[0].Version, Baseline+1)
+    assert.NilError(t, m.Check())
+
+    rows, err := m.DB.Query(`SELECT title, slug, user_id FROM snippets ORDER BY id`)
+    assert.NilError(t, err)
+    defer rows.Close()
+
+    // the old snippets are kept, with no owner, and each is given a slug
+    // that starts with a letter so it cannot be taken for a numeric id
+    slugRX := regexp.MustCompile(`^[a-km-zA-HJ-NP-Z][a-km-zA-HJ-NP-Z2-9]{9}$`)
+    var titles []string
+    slugs := map[string]bool{}
+
+    for rows.Next() {
+        var title, slug string
+        var userID sql.NullInt64
+
+        err := rows.Scan(&title, &slug, &userID)
+        assert.NilError(t, err)
+
+        assert.Equal(t, slugRX.MatchString(slug), true)
+        assert.Equal(t, slugs[slug], false)
+        assert.Equal(t, userID.Valid, false)
+
+        titles = append(titles, title)
+        slugs[slug] = true
+    }
+    assert.NilError(t, rows.Err())
+
+    assert.Equal(t, len(titles), 2)
+    assert.Equal(t, titles[0], "An old silent pond")
+}
+
+func TestUpPartialBaseline(t *testing.T) {
+    m := newSQLiteMigrator(t)
+
+    _, err := m.DB.Exec(`CREATE TABLE snippets (id INTEGER NOT NULL PRIMARY KEY)`)
+    assert.NilError(t, err)
+
+    _, err = m.Up()
+    if err == nil {
+        t.Fatal("expected an error")
+    }
+    assert.StringContains(t, err.Error(), "not users, sessions")
+}
@@ -1,3 +1 @@
-DROP TABLE users;
-
 DROP TABLE snippets;
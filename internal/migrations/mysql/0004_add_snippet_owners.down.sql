@@ -0,0 +1,2 @@
+ALTER TABLE snippets DROP FOREIGN KEY snippets_fk_user_id;
+ALTER TABLE snippets DROP COLUMN user_id;
@@ -0,0 +1,26 @@
+-- every existing snippet is given a random slug made of the same characters
+-- as the slugs the application generates. The first character is always a
+-- letter, so that a slug can never be mistaken for a legacy numeric id. With
+-- 57 possible characters a clash between two of them is vanishingly rare,
+-- and the unique constraint would catch one.
+--
+-- Slugs are compared byte for byte, since the default collation ignores
+-- case and would let abcdefghij find the snippet at ABCDEFGHIJ
+ALTER TABLE snippets ADD COLUMN slug CHAR(10) CHARACTER SET ascii COLLATE ascii_bin NULL AFTER id;
+
+UPDATE snippets SET slug = CONCAT(
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ', 1 + FLOOR(RAND() * 49), 1),
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + FLOOR(RAND() * 57), 1),
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + FLOOR(RAND() * 57), 1),
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + FLOOR(RAND() * 57), 1),
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + FLOOR(RAND() * 57), 1),
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + FLOOR(RAND() * 57), 1),
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + FLOOR(RAND() * 57), 1),
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + FLOOR(RAND() * 57), 1),
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + FLOOR(RAND() * 57), 1),
+    SUBSTRING('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + FLOOR(RAND() * 57), 1)
+) WHERE slug IS NULL;
+
+ALTER TABLE snippets MODIFY slug CHAR(10) CHARACTER SET ascii COLLATE ascii_bin NOT NULL;
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_slug UNIQUE (slug);
@@ -0,0 +1,5 @@
+-- snippets that never expire are given an expiry so far away that they
+-- still never will
+UPDATE snippets SET expires = '9999-12-31 00:00:00' WHERE expires IS NULL;
+
+ALTER TABLE snippets MODIFY expires DATETIME NOT NULL;
@@ -0,0 +1,12 @@
+CREATE TABLE snippets_archive (
+    id INTEGER NOT NULL PRIMARY KEY,
+    slug CHAR(10) CHARACTER SET ascii COLLATE ascii_bin NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    language VARCHAR(20) NOT NULL,
+    visibility ENUM('public', 'unlisted', 'private') NOT NULL,
+    user_id INTEGER NULL,
+    archived DATETIME NOT NULL
+);
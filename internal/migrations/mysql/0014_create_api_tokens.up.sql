@@ -0,0 +1,13 @@
+CREATE TABLE api_tokens (
+    id INTEGER NOT NULL PRIMARY KEY AUTO_INCREMENT,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix CHAR(12) NOT NULL,
+    hashed_token CHAR(64) NOT NULL,
+    scope ENUM('read', 'write') NOT NULL DEFAULT 'read',
+    created DATETIME NOT NULL,
+    last_used DATETIME NULL
+);
+
+ALTER TABLE api_tokens ADD CONSTRAINT api_tokens_uc_hashed_token UNIQUE (hashed_token);
+ALTER TABLE api_tokens ADD CONSTRAINT api_tokens_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,2 @@
+-- search has to use this exact expression for the index to be used
+CREATE INDEX idx_snippets_fulltext ON snippets USING GIN (to_tsvector('english', title || ' ' || content));
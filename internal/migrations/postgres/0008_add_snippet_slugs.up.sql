@@ -0,0 +1,23 @@
+-- every existing snippet is given a random slug made of the same characters
+-- as the slugs the application generates. The first character is always a
+-- letter, so that a slug can never be mistaken for a legacy numeric id. With
+-- 57 possible characters a clash between two of them is vanishingly rare,
+-- and the unique constraint would catch one
+ALTER TABLE snippets ADD COLUMN slug CHAR(10) NULL;
+
+UPDATE snippets SET slug =
+    substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ', 1 + floor(random() * 49)::int, 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + floor(random() * 57)::int, 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + floor(random() * 57)::int, 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + floor(random() * 57)::int, 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + floor(random() * 57)::int, 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + floor(random() * 57)::int, 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + floor(random() * 57)::int, 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + floor(random() * 57)::int, 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + floor(random() * 57)::int, 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + floor(random() * 57)::int, 1)
+WHERE slug IS NULL;
+
+ALTER TABLE snippets ALTER COLUMN slug SET NOT NULL;
+
+ALTER TABLE snippets ADD CONSTRAINT snippets_uc_slug UNIQUE (slug);
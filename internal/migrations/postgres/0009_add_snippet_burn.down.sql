@@ -0,0 +1 @@
+ALTER TABLE snippets DROP COLUMN burn_after_reading, DROP COLUMN burned;
@@ -0,0 +1 @@
+ALTER TABLE snippets ADD COLUMN burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN burned TIMESTAMPTZ NULL;
@@ -0,0 +1,2 @@
+-- a NULL expiry means the snippet never expires
+ALTER TABLE snippets ALTER COLUMN expires DROP NOT NULL;
@@ -0,0 +1,12 @@
+CREATE TABLE snippets_archive (
+    id INTEGER NOT NULL PRIMARY KEY,
+    slug CHAR(10) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created TIMESTAMPTZ NOT NULL,
+    expires TIMESTAMPTZ NOT NULL,
+    language VARCHAR(20) NOT NULL,
+    visibility VARCHAR(10) NOT NULL,
+    user_id INTEGER NULL,
+    archived TIMESTAMPTZ NOT NULL
+);
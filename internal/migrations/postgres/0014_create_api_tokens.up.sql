@@ -0,0 +1,15 @@
+CREATE TABLE api_tokens (
+    id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    prefix CHAR(12) NOT NULL,
+    hashed_token CHAR(64) NOT NULL,
+    scope VARCHAR(10) NOT NULL DEFAULT 'read' CHECK (scope IN ('read', 'write')),
+    created TIMESTAMPTZ NOT NULL,
+    last_used TIMESTAMPTZ NULL
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
+
+ALTER TABLE api_tokens ADD CONSTRAINT api_tokens_uc_hashed_token UNIQUE (hashed_token);
+ALTER TABLE api_tokens ADD CONSTRAINT api_tokens_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
@@ -0,0 +1,9 @@
+-- the table that github.com/alexedwards/scs/sqlite3store expects. expiry
+-- is a Julian day number
+CREATE TABLE sessions (
+    token TEXT PRIMARY KEY,
+    data BLOB NOT NULL,
+    expiry REAL NOT NULL
+);
+
+CREATE INDEX sessions_expiry_idx ON sessions (expiry);
@@ -0,0 +1,21 @@
+-- SQLite cannot drop a column that is part of a foreign key, so the table
+-- is rebuilt without it. Nothing else refers to snippets at this version.
+-- The AUTOINCREMENT counter is carried over so that ids are not reused
+CREATE TABLE snippets_new (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL
+);
+
+INSERT INTO sqlite_sequence (name, seq) SELECT 'snippets_new', seq FROM sqlite_sequence WHERE name = 'snippets';
+
+INSERT INTO snippets_new (id, title, content, created, expires)
+SELECT id, title, content, created, expires FROM snippets;
+
+DROP TABLE snippets;
+
+ALTER TABLE snippets_new RENAME TO snippets;
+
+CREATE INDEX idx_snippets_created ON snippets(created);
@@ -0,0 +1,2 @@
+-- snippets created before they had owners are left with a NULL user_id
+ALTER TABLE snippets ADD COLUMN user_id INTEGER NULL CONSTRAINT snippets_fk_user_id REFERENCES users(id);
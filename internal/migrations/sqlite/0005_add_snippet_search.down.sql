@@ -0,0 +1 @@
+-- there is no full-text index to drop in SQLite
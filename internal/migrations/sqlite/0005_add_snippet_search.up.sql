@@ -0,0 +1,2 @@
+-- SQLite has no full-text index, since the FTS5 extension is not built in.
+-- Search uses LIKE instead
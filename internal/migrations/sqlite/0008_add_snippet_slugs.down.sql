@@ -0,0 +1,3 @@
+DROP INDEX snippets_uc_slug;
+
+ALTER TABLE snippets DROP COLUMN slug;
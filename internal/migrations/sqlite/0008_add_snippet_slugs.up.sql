@@ -0,0 +1,49 @@
+-- every existing snippet is given a random slug made of the same characters
+-- as the slugs the application generates. The first character is always a
+-- letter, so that a slug can never be mistaken for a legacy numeric id. With
+-- 57 possible characters a clash between two of them is vanishingly rare,
+-- and the unique constraint would catch one
+ALTER TABLE snippets ADD COLUMN slug CHAR(10) NULL;
+
+UPDATE snippets SET slug =
+    substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ', 1 + abs(random() % 49), 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + abs(random() % 57), 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + abs(random() % 57), 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + abs(random() % 57), 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + abs(random() % 57), 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + abs(random() % 57), 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + abs(random() % 57), 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + abs(random() % 57), 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + abs(random() % 57), 1)
+    || substr('abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789', 1 + abs(random() % 57), 1)
+WHERE slug IS NULL;
+
+-- SQLite cannot change the constraints of a column, so the table is rebuilt
+-- with slug NOT NULL. Nothing else refers to snippets at this version. The
+-- AUTOINCREMENT counter is carried over so that ids are not reused
+CREATE TABLE snippets_new (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NOT NULL,
+    user_id INTEGER NULL CONSTRAINT snippets_fk_user_id REFERENCES users(id),
+    language VARCHAR(20) NOT NULL DEFAULT 'plaintext',
+    visibility VARCHAR(10) NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'unlisted', 'private')),
+    slug CHAR(10) NOT NULL
+);
+
+INSERT INTO sqlite_sequence (name, seq) SELECT 'snippets_new', seq FROM sqlite_sequence WHERE name = 'snippets';
+
+INSERT INTO snippets_new (id, title, content, created, expires, user_id, language, visibility, slug)
+SELECT id, title, content, created, expires, user_id, language, visibility, slug FROM snippets;
+
+DROP TABLE snippets;
+
+ALTER TABLE snippets_new RENAME TO snippets;
+
+CREATE INDEX idx_snippets_created ON snippets(created);
+
+-- SQLite cannot add a constraint to an existing table, but a unique index
+-- does the same job
+CREATE UNIQUE INDEX snippets_uc_slug ON snippets(slug);
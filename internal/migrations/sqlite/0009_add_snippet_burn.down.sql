@@ -0,0 +1,2 @@
+ALTER TABLE snippets DROP COLUMN burn_after_reading;
+ALTER TABLE snippets DROP COLUMN burned;
@@ -0,0 +1,31 @@
+-- a NULL expiry means the snippet never expires. SQLite cannot change the
+-- constraints of a column, so the table is rebuilt. Nothing else refers to
+-- snippets at this version. The AUTOINCREMENT counter is carried over so
+-- that ids are not reused
+CREATE TABLE snippets_new (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    expires DATETIME NULL,
+    user_id INTEGER NULL CONSTRAINT snippets_fk_user_id REFERENCES users(id),
+    language VARCHAR(20) NOT NULL DEFAULT 'plaintext',
+    visibility VARCHAR(10) NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'unlisted', 'private')),
+    slug CHAR(10) NOT NULL,
+    burn_after_reading BOOLEAN NOT NULL DEFAULT FALSE,
+    burned DATETIME NULL,
+    hashed_password CHAR(60) NULL
+);
+
+INSERT INTO sqlite_sequence (name, seq) SELECT 'snippets_new', seq FROM sqlite_sequence WHERE name = 'snippets';
+
+INSERT INTO snippets_new (id, title, content, created, expires, user_id, language, visibility, slug, burn_after_reading, burned, hashed_password)
+SELECT id, title, content, created, expires, user_id, language, visibility, slug, burn_after_reading, burned, hashed_password FROM snippets;
+
+DROP TABLE snippets;
+
+ALTER TABLE snippets_new RENAME TO snippets;
+
+CREATE INDEX idx_snippets_created ON snippets(created);
+
+CREATE UNIQUE INDEX snippets_uc_slug ON snippets(slug);
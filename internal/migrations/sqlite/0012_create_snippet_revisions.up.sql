@@ -0,0 +1,20 @@
+-- every existing snippet gets a version 1 holding its text as it stands, so
+-- that editing it later doesn't lose the original. Snippets with no owner
+-- are skipped on purpose: nobody can edit them, so they never get a history
+-- to start. Burned snippets are skipped too, since they keep no revisions
+CREATE TABLE snippet_revisions (
+    id INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,
+    snippet_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    created DATETIME NOT NULL,
+    user_id INTEGER NOT NULL,
+    CONSTRAINT snippet_revisions_uc_version UNIQUE (snippet_id, version),
+    CONSTRAINT snippet_revisions_fk_snippet_id FOREIGN KEY (snippet_id) REFERENCES snippets(id) ON DELETE CASCADE,
+    CONSTRAINT snippet_revisions_fk_user_id FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+INSERT INTO snippet_revisions (snippet_id, version, user_id, title, content, created)
+SELECT id, 1, user_id, title, content, created FROM snippets
+WHERE user_id IS NOT NULL AND burned IS NULL;
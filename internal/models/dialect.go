@@ -0,0 +1,83 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Dialect is the SQL dialect of the database behind a model, so that one
+// implementation of each model serves every database. Queries are written
+// with ? placeholders, and the current time is passed in as a parameter
+// rather than asked of the database, since SQLite has no function for it
+// that matches how the driver stores a time.Time
+type Dialect string
+
+// the dialects the models can be used with, named as in the migrations
+// package. A blank Dialect means MySQL
+const (
+    MySQL    Dialect = "mysql"
+    SQLite   Dialect = "sqlite"
+    Postgres Dialect = "postgres"
+)
+
+// bind rewrites the ? placeholders of a query into the $1, $2, ... form
+// that PostgreSQL uses. Queries for other dialects are left alone
+func (d Dialect) bind(query string) string {
+    if d != Postgres {
+        return query
+    }
+
+    var b strings.Builder
+    n := 0
+
+    for _, r := range query {
+        if r == '?' {
+            n++
+            fmt.Fprintf(&b, "$%d", n)
+            continue
+        }
+        b.WriteRune(r)
+    }
+
+    return b.String()
+}
+
+// insert runs an INSERT statement inside tx and returns the id of the new
+// row. PostgreSQL has no LastInsertId(), so there the statement returns
+// the id itself
+func (d Dialect) insert(ctx context.Context, tx *sql.Tx, stmt string, args ...any) (int, error) {
+    if d == Postgres {
+        var id int
+        err := tx.QueryRowContext(ctx, d.bind(stmt+` RETURNING id`), args...).Scan(&id)
+        return id, err
+    }
+
+    result, err := tx.ExecContext(ctx, stmt, args...)
+    if err != nil {
+        return 0, err
+    }
+
+    id, err := result.LastInsertId()
+    return int(id), err
+}
+
+// forUpdate returns the clause that locks the rows a SELECT reads until
+// the transaction ends. SQLite has no such clause and needs none, since
+// its transactions take the write lock as soon as they begin
+func (d Dialect) forUpdate() string {
+    if d == SQLite {
+        return ""
+    }
+
+    return " FOR UPDATE"
+}
+
+// now returns the current time as the models store it. Times are kept to
+// the second in UTC so that they all have the same text format in SQLite,
+// which makes comparing them as strings the same as comparing them as times
+func now() time.Time {
+    return time.Now().UTC().Truncate(time.Second)
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+    "testing"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestDialectBind(t *testing.T) {
+    query := `SELECT id FROM snippets WHERE expires <= ? ORDER BY id LIMIT ?`
+
+    for _, d := range []Dialect{"", MySQL, SQLite} {
+        assert.Equal(t, d.bind(query), query)
+    }
+
+    assert.Equal(t, Postgres.bind(query), `SELECT id FROM snippets WHERE expires <= $1 ORDER BY id LIMIT $2`)
+}
@@ -2,6 +2,11 @@ package models
 
 import (
     "errors"
+    "strings"
+
+    "github.com/go-sql-driver/mysql"
+    "github.com/lib/pq"
+    "github.com/mattn/go-sqlite3"
 )
 
 var (
@@ -11,3 +16,37 @@ var (
 
     ErrDuplicateEmail = errors.New("models: duplicate email")
 )
+
+// uniqueKey identifies a unique constraint in the way each database reports
+// a violation of it. MySQL and PostgreSQL name the constraint, while SQLite
+// names the columns it covers
+type uniqueKey struct {
+    name    string
+    columns string
+}
+
+var (
+    usersEmailKey   = uniqueKey{name: "users_uc_email", columns: "users.email"}
+    snippetsSlugKey = uniqueKey{name: "snippets_uc_slug", columns: "snippets.slug"}
+)
+
+// isDuplicate reports whether err is a violation of the unique constraint
+// key, whichever database it came from
+func isDuplicate(err error, key uniqueKey) bool {
+    var mySQLError *mysql.MySQLError
+    if errors.As(err, &mySQLError) {
+        return mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, key.name)
+    }
+
+    var pqError *pq.Error
+    if errors.As(err, &pqError) {
+        return pqError.Code == "23505" && pqError.Constraint == key.name
+    }
+
+    var sqliteError sqlite3.Error
+    if errors.As(err, &sqliteError) {
+        return sqliteError.ExtendedCode == sqlite3.ErrConstraintUnique && strings.Contains(sqliteError.Error(), key.columns)
+    }
+
+    return false
+}
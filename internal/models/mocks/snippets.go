@@ -1,34 +1,239 @@
 package mocks
 
 import (
+    "context"
+    "strings"
     "time"
 
     "github.com/j-clemons/snippetbox/internal/models"
 )
 
 var mockSnippet = models.Snippet{
-    ID:      1,
-    Title:   "An old silent pond",
-    Content: "An old silent pond...",
-    Created: time.Now(),
-    Expires: time.Now(),
+    ID:         1,
+    Slug:       "pond234567",
+    Title:      "An old silent pond",
+    Content:    "An old silent pond...",
+    Created:    time.Now(),
+    Expires:    time.Now(),
+    Language:   "plaintext",
+    Visibility: models.VisibilityPublic,
+    UserID:     1,
+    UserName:   "Alice Jones",
 }
 
+// mockOtherSnippet belongs to a user other than the mock logged in user,
+// so it can be used to exercise the ownership checks
+var mockOtherSnippet = models.Snippet{
+    ID:         3,
+    Slug:       "forest2345",
+    Title:      "Over the wintry forest",
+    Content:    "Over the wintry forest, winds howl in rage...",
+    Created:    time.Now(),
+    Expires:    time.Now(),
+    Language:   "plaintext",
+    Visibility: models.VisibilityPublic,
+    UserID:     2,
+    UserName:   "Bob Smith",
+}
+
+// mockPrivateSnippet is private to the mock logged in user
+var mockPrivateSnippet = models.Snippet{
+    ID:         4,
+    Slug:       "autumn2345",
+    Title:      "First autumn morning",
+    Content:    "First autumn morning, the mirror I stare into shows my father's face.",
+    Created:    time.Now(),
+    Expires:    time.Now(),
+    Language:   "plaintext",
+    Visibility: models.VisibilityPrivate,
+    UserID:     1,
+    UserName:   "Alice Jones",
+}
+
+var mockRevisions = []models.Revision{
+    {
+        ID:        2,
+        SnippetID: 1,
+        Version:   2,
+        Title:     "An old silent pond",
+        Content:   "An old silent pond...",
+        Created:   time.Now(),
+        UserID:    1,
+        UserName:  "Alice Jones",
+    },
+    {
+        ID:        1,
+        SnippetID: 1,
+        Version:   1,
+        Title:     "An old silent pond",
+        Content:   "An old pond...",
+        Created:   time.Now(),
+        UserID:    1,
+        UserName:  "Alice Jones",
+    },
+}
+
+// mockBurnSnippet is destroyed after it is read
+var mockBurnSnippet = models.Snippet{
+    ID:               5,
+    Slug:             "burn234567",
+    Title:            "Temple bells die out",
+    Content:          "Temple bells die out. The fragrant blossoms remain.",
+    Created:          time.Now(),
+    Expires:          time.Now(),
+    Language:         "plaintext",
+    Visibility:       models.VisibilityUnlisted,
+    BurnAfterReading: true,
+    UserID:           2,
+    UserName:         "Bob Smith",
+}
+
+// mockLockedSnippet can only be read with the passphrase "pa$$phrase"
+var mockLockedSnippet = models.Snippet{
+    ID:         6,
+    Slug:       "gate234567",
+    Title:      "The light of a candle",
+    Content:    "The light of a candle is transferred to another candle.",
+    Created:    time.Now(),
+    Expires:    time.Now(),
+    Language:   "plaintext",
+    Visibility: models.VisibilityUnlisted,
+    Locked:     true,
+    UserID:     2,
+    UserName:   "Bob Smith",
+}
+
+// mockNewSnippet is the snippet created by every call to Insert
+var mockNewSnippet = models.Snippet{
+    ID:         7,
+    Slug:       "fuji234567",
+    Title:      "O snail",
+    Content:    "O snail\nClimb Mount Fuji,\nBut slowly, slowly!",
+    Created:    time.Now(),
+    Expires:    time.Now().Add(7 * 24 * time.Hour),
+    Language:   "plaintext",
+    Visibility: models.VisibilityPublic,
+    UserID:     1,
+    UserName:   "Alice Jones",
+}
+
+// mockSlowSlug names a snippet that can never be fetched because the
+// database always takes too long
+const mockSlowSlug = "timeout234"
+
 type SnippetModel struct{}
 
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-    return 2, nil
+func (m *SnippetModel) Insert(ctx context.Context, title string, content string, language string, visibility string, expires time.Time, burn bool, password string, userID int) (string, error) {
+    return mockNewSnippet.Slug, nil
 }
 
-func (m *SnippetModel) Get(id int) (models.Snippet, error) {
+func (m *SnippetModel) Get(ctx context.Context, id int) (models.Snippet, error) {
     switch id {
     case 1:
         return mockSnippet, nil
+    case 3:
+        return mockOtherSnippet, nil
+    case 4:
+        return mockPrivateSnippet, nil
+    case 5:
+        return mockBurnSnippet, nil
+    case 6:
+        return mockLockedSnippet, nil
     default:
         return models.Snippet{}, models.ErrNoRecord
     }
 }
 
-func (m *SnippetModel) Latest() ([]models.Snippet, error) {
+func (m *SnippetModel) GetBySlug(ctx context.Context, slug string) (models.Snippet, error) {
+    if slug == mockSlowSlug {
+        return models.Snippet{}, context.DeadlineExceeded
+    }
+
+    for _, s := range []models.Snippet{mockSnippet, mockOtherSnippet, mockPrivateSnippet, mockBurnSnippet, mockLockedSnippet, mockNewSnippet} {
+        if s.Slug == slug {
+            return s, nil
+        }
+    }
+
+    return models.Snippet{}, models.ErrNoRecord
+}
+
+func (m *SnippetModel) Latest(ctx context.Context) ([]models.Snippet, error) {
     return []models.Snippet{mockSnippet}, nil
 }
+
+func (m *SnippetModel) Archive(ctx context.Context, before int, after int, limit int) ([]models.Snippet, error) {
+    if (before == 0 || before > mockSnippet.ID) && after < mockSnippet.ID {
+        return []models.Snippet{mockSnippet}, nil
+    }
+
+    return nil, nil
+}
+
+func (m *SnippetModel) Search(ctx context.Context, query string, page int, perPage int) ([]models.Snippet, error) {
+    if page == 1 && strings.Contains(strings.ToLower(mockSnippet.Content), strings.ToLower(query)) {
+        return []models.Snippet{mockSnippet}, nil
+    }
+
+    return nil, nil
+}
+
+func (m *SnippetModel) Update(ctx context.Context, id int, title string, content string, language string, visibility string, expires time.Time) error {
+    switch id {
+    case 1, 3, 4:
+        return nil
+    default:
+        return models.ErrNoRecord
+    }
+}
+
+func (m *SnippetModel) Burn(ctx context.Context, id int) (models.Snippet, error) {
+    switch id {
+    case 5:
+        return mockBurnSnippet, nil
+    default:
+        return models.Snippet{}, models.ErrNoRecord
+    }
+}
+
+func (m *SnippetModel) Unlock(ctx context.Context, id int, password string) error {
+    if id == mockLockedSnippet.ID && password == "pa$$phrase" {
+        return nil
+    }
+
+    return models.ErrInvalidCredentials
+}
+
+func (m *SnippetModel) Delete(ctx context.Context, id int) error {
+    switch id {
+    case 1, 3, 4:
+        return nil
+    default:
+        return models.ErrNoRecord
+    }
+}
+
+func (m *SnippetModel) Purge(ctx context.Context, limit int, archive bool) (int, error) {
+    return 0, nil
+}
+
+func (m *SnippetModel) Revisions(ctx context.Context, snippetID int) ([]models.Revision, error) {
+    switch snippetID {
+    case 1:
+        return mockRevisions, nil
+    default:
+        return nil, nil
+    }
+}
+
+func (m *SnippetModel) GetRevision(ctx context.Context, snippetID int, version int) (models.Revision, error) {
+    if snippetID == 1 {
+        for _, r := range mockRevisions {
+            if r.Version == version {
+                return r, nil
+            }
+        }
+    }
+
+    return models.Revision{}, models.ErrNoRecord
+}
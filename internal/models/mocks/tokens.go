@@ -0,0 +1,67 @@
+package mocks
+
+import (
+    "context"
+    "time"
+
+    "github.com/j-clemons/snippetbox/internal/models"
+)
+
+// mockReadToken belongs to the mock logged in user and can only read
+var mockReadToken = models.Token{
+    ID:      1,
+    UserID:  1,
+    Name:    "CI",
+    Prefix:  "sbx_readread",
+    Scope:   models.ScopeRead,
+    Created: time.Now(),
+}
+
+// mockWriteToken belongs to the mock logged in user and can do anything
+var mockWriteToken = models.Token{
+    ID:       2,
+    UserID:   1,
+    Name:     "Laptop",
+    Prefix:   "sbx_writewri",
+    Scope:    models.ScopeWrite,
+    Created:  time.Now(),
+    LastUsed: time.Now(),
+}
+
+// the tokens themselves, which in a real database are only stored hashed
+var mockTokens = map[string]models.Token{
+    "sbx_readreadreadreadreadreadreadreadreadread": mockReadToken,
+    "sbx_writewritewritewritewritewritewritewrite": mockWriteToken,
+}
+
+type TokenModel struct{}
+
+func (m *TokenModel) Insert(ctx context.Context, userID int, name string, scope string) (string, error) {
+    return "sbx_new2new2new2new2new2new2new2new2new2new2", nil
+}
+
+func (m *TokenModel) Authenticate(ctx context.Context, token string) (models.Token, error) {
+    t, ok := mockTokens[token]
+    if !ok {
+        return models.Token{}, models.ErrInvalidCredentials
+    }
+
+    return t, nil
+}
+
+func (m *TokenModel) List(ctx context.Context, userID int) ([]models.Token, error) {
+    switch userID {
+    case 1:
+        return []models.Token{mockWriteToken, mockReadToken}, nil
+    default:
+        return nil, nil
+    }
+}
+
+func (m *TokenModel) Delete(ctx context.Context, id int, userID int) error {
+    if userID == 1 && (id == mockReadToken.ID || id == mockWriteToken.ID) {
+        return nil
+    }
+
+    return models.ErrNoRecord
+}
@@ -1,12 +1,13 @@
 package mocks
 
 import (
+    "context"
     "github.com/j-clemons/snippetbox/internal/models"
 )
 
 type UserModel struct{}
 
-func (m *UserModel) Insert(name, email, password string) error {
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) error {
     switch email {
     case "dupe@example.com":
         return models.ErrDuplicateEmail
@@ -15,7 +16,7 @@ func (m *UserModel) Insert(name, email, password string) error {
     }
 }
 
-func (m *UserModel) Authenticate(email, password string) (int, error) {
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
     if email == "alice@example.com" && password == "pa$$word" {
         return 1, nil
     }
@@ -23,7 +24,7 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
     return 0, models.ErrInvalidCredentials
 }
 
-func (m *UserModel) Exists(id int) (bool, error) {
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
     switch id {
     case 1:
         return true, nil
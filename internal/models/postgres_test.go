@@ -0,0 +1,78 @@
+package models
+
+import (
+    "context"
+    "testing"
+    "time"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestPostgresUserModel(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newPostgresTestDB(t)
+    m := UserModel{DB: db, Dialect: Postgres, BcryptCost: 4}
+    ctx := context.Background()
+
+    exists, err := m.Exists(ctx, 1)
+    assert.NilError(t, err)
+    assert.Equal(t, exists, true)
+
+    err = m.Insert(ctx, "Bob", "bob@example.com", "pa$$word")
+    assert.NilError(t, err)
+
+    err = m.Insert(ctx, "Bobby", "bob@example.com", "pa$$word")
+    assert.Equal(t, err, ErrDuplicateEmail)
+
+    id, err := m.Authenticate(ctx, "bob@example.com", "pa$$word")
+    assert.NilError(t, err)
+    assert.Equal(t, id, 2)
+
+    _, err = m.Authenticate(ctx, "bob@example.com", "wrong")
+    assert.Equal(t, err, ErrInvalidCredentials)
+}
+
+func TestPostgresSnippetModel(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newPostgresTestDB(t)
+    m := SnippetModel{DB: db, Dialect: Postgres, BcryptCost: 4}
+    ctx := context.Background()
+
+    slug, err := m.Insert(ctx, "An old silent pond", "A frog jumps into the pond", "plaintext", VisibilityPublic, time.Now().Add(time.Hour), false, "", 1)
+    assert.NilError(t, err)
+
+    s, err := m.GetBySlug(ctx, slug)
+    assert.NilError(t, err)
+    assert.Equal(t, s.UserName, "Alice Jones")
+
+    err = m.Update(ctx, s.ID, "Over the wintry forest", "Winds howl in rage", "plaintext", VisibilityPublic, time.Time{})
+    assert.NilError(t, err)
+
+    revisions, err := m.Revisions(ctx, s.ID)
+    assert.NilError(t, err)
+    assert.Equal(t, len(revisions), 2)
+
+    found, err := m.Search(ctx, "winds", 1, 10)
+    assert.NilError(t, err)
+    assert.Equal(t, len(found), 1)
+
+    err = m.Delete(ctx, s.ID)
+    assert.NilError(t, err)
+
+    _, err = m.Get(ctx, s.ID)
+    assert.Equal(t, err, ErrNoRecord)
+}
+
+func TestPostgresTokenModel(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    testTokenModel(t, &TokenModel{DB: newPostgresTestDB(t), Dialect: Postgres})
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "time"
+)
+
+// Revision is a snapshot of a snippet's title and content as saved at
+// a point in time. Versions are numbered from 1 for each snippet
+type Revision struct {
+    ID        int
+    SnippetID int
+    Version   int
+    Title     string
+    Content   string
+    Created   time.Time
+    UserID    int
+    UserName  string
+}
+
+// insertRevision copies the current state of a snippet into the
+// snippet_revisions table as its next version, created at the given time.
+// It must be called inside the same transaction that inserted or updated
+// the snippet. If there is no snippet with the id we return ErrNoRecord
+func (m *SnippetModel) insertRevision(ctx context.Context, tx *sql.Tx, snippetID int, created time.Time) error {
+    stmt := `INSERT INTO snippet_revisions (snippet_id, version, title, content, created, user_id)
+    SELECT s.id, COALESCE(MAX(r.version), 0) + 1, s.title, s.content, ?, s.user_id
+    FROM snippets s LEFT JOIN snippet_revisions r ON r.snippet_id = s.id
+    WHERE s.id = ?
+    GROUP BY s.id`
+
+    result, err := tx.ExecContext(ctx, m.Dialect.bind(stmt), created, snippetID)
+    if err != nil {
+        return err
+    }
+
+    rows, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rows == 0 {
+        return ErrNoRecord
+    }
+
+    return nil
+}
+
+// return every revision of a snippet, newest first
+func (m *SnippetModel) Revisions(ctx context.Context, snippetID int) ([]Revision, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    stmt := `SELECT r.id, r.snippet_id, r.version, r.title, r.content, r.created, r.user_id, u.name
+    FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+    WHERE r.snippet_id = ? ORDER BY r.version DESC`
+
+    rows, err := m.DB.QueryContext(ctx, m.Dialect.bind(stmt), snippetID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var revisions []Revision
+
+    for rows.Next() {
+        var r Revision
+
+        err := rows.Scan(&r.ID, &r.SnippetID, &r.Version, &r.Title, &r.Content, &r.Created, &r.UserID, &r.UserName)
+        if err != nil {
+            return nil, err
+        }
+
+        revisions = append(revisions, r)
+    }
+
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return revisions, nil
+}
+
+// return a single revision of a snippet by its version number
+func (m *SnippetModel) GetRevision(ctx context.Context, snippetID int, version int) (Revision, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    stmt := `SELECT r.id, r.snippet_id, r.version, r.title, r.content, r.created, r.user_id, u.name
+    FROM snippet_revisions r INNER JOIN users u ON u.id = r.user_id
+    WHERE r.snippet_id = ? AND r.version = ?`
+
+    var r Revision
+
+    err := m.DB.QueryRowContext(ctx, m.Dialect.bind(stmt), snippetID, version).Scan(&r.ID, &r.SnippetID, &r.Version, &r.Title, &r.Content, &r.Created, &r.UserID, &r.UserName)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return Revision{}, ErrNoRecord
+        } else {
+            return Revision{}, err
+        }
+    }
+
+    return r, nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+    "crypto/rand"
+    "fmt"
+    "math/big"
+    "regexp"
+    "strings"
+)
+
+// slugAlphabet holds the characters a slug is made of. They are all safe
+// to use in a URL without escaping
+const slugAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// slugLength is the number of characters in a slug. With 57 possible
+// characters that gives roughly 2^58 slugs, far too many to enumerate
+const slugLength = 10
+
+// slugAttempts is how many times Insert() tries a fresh slug before giving
+// up, should the ones it generates already be taken
+const slugAttempts = 5
+
+// SlugRX matches strings that have the form of a slug
+var SlugRX = regexp.MustCompile(fmt.Sprintf("^[%s]{%d}$", slugAlphabet, slugLength))
+
+// newSlug returns a random slug using a cryptographically secure source of
+// randomness. Easily confused characters such as l, 1, O and 0 are left
+// out of the alphabet so that slugs can be read out or typed by hand
+func newSlug() (string, error) {
+    return generateSlug(randomString)
+}
+
+// generateSlug asks random for slugs until it gets one with a letter in
+// it. A slug made only of digits would be taken for the numeric id of a
+// snippet from before there were slugs, and redirected
+func generateSlug(random func(n int) (string, error)) (string, error) {
+    for {
+        slug, err := random(slugLength)
+        if err != nil {
+            return "", err
+        }
+
+        if strings.Trim(slug, "0123456789") != "" {
+            return slug, nil
+        }
+    }
+}
+
+// randomString returns n characters chosen at random from slugAlphabet
+func randomString(n int) (string, error) {
+    b := make([]byte, n)
+    max := big.NewInt(int64(len(slugAlphabet)))
+
+    for i := range b {
+        n, err := rand.Int(rand.Reader, max)
+        if err != nil {
+            return "", err
+        }
+        b[i] = slugAlphabet[n.Int64()]
+    }
+
+    return string(b), nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+    "strconv"
+    "testing"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestNewSlug(t *testing.T) {
+    seen := make(map[string]bool)
+
+    for i := 0; i < 1000; i++ {
+        slug, err := newSlug()
+        assert.NilError(t, err)
+
+        assert.Equal(t, SlugRX.MatchString(slug), true)
+        assert.Equal(t, seen[slug], false)
+
+        seen[slug] = true
+    }
+}
+
+func TestGenerateSlugNeedsLetter(t *testing.T) {
+    // the first two slugs are all digits, which would look like legacy ids
+    candidates := []string{"2345678923", "9999999999", "2345a78923"}
+    calls := 0
+
+    random := func(n int) (string, error) {
+        assert.Equal(t, n, slugLength)
+
+        slug := candidates[calls]
+        calls++
+        return slug, nil
+    }
+
+    slug, err := generateSlug(random)
+    assert.NilError(t, err)
+    assert.Equal(t, slug, "2345a78923")
+    assert.Equal(t, calls, 3)
+
+    _, err = strconv.Atoi(slug)
+    if err == nil {
+        t.Errorf("slug %q can be mistaken for a numeric id", slug)
+    }
+}
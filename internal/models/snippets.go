@@ -1,71 +1,172 @@
 package models
 
 import (
+    "context"
     "database/sql"
     "errors"
+    "slices"
+    "strings"
     "time"
+
+    "golang.org/x/crypto/bcrypt"
+)
+
+// the visibility levels a snippet can have. Public snippets are listed
+// everywhere, unlisted snippets can be viewed by anyone with the link, and
+// private snippets can only be viewed by their owner
+const (
+    VisibilityPublic   = "public"
+    VisibilityUnlisted = "unlisted"
+    VisibilityPrivate  = "private"
 )
 
 type Snippet struct {
-    ID      int
-    Title   string
-    Content string
-    Created time.Time
-    Expires time.Time
+    ID               int
+    Slug             string
+    Title            string
+    Content          string
+    Created          time.Time
+    // Expires is the zero time for snippets that never expire
+    Expires          time.Time
+    Language         string
+    Visibility       string
+    // BurnAfterReading snippets are destroyed the first time they are read
+    BurnAfterReading bool
+    // Locked snippets need a passphrase before they can be read
+    Locked           bool
+    // UserID is 0 for snippets created before snippets had owners, which
+    // nobody can edit or delete
+    UserID           int
+    UserName         string
 }
 
 type SnippetModelInterface interface {
-    Insert(title string, content string, expires int) (int, error)
-    Get(id int) (Snippet, error)
-    Latest() ([]Snippet, error)
+    Insert(ctx context.Context, title string, content string, language string, visibility string, expires time.Time, burn bool, password string, userID int) (string, error)
+    Get(ctx context.Context, id int) (Snippet, error)
+    GetBySlug(ctx context.Context, slug string) (Snippet, error)
+    Latest(ctx context.Context) ([]Snippet, error)
+    Archive(ctx context.Context, before int, after int, limit int) ([]Snippet, error)
+    Search(ctx context.Context, query string, page int, perPage int) ([]Snippet, error)
+    Update(ctx context.Context, id int, title string, content string, language string, visibility string, expires time.Time) error
+    Delete(ctx context.Context, id int) error
+    Purge(ctx context.Context, limit int, archive bool) (int, error)
+    Burn(ctx context.Context, id int) (Snippet, error)
+    Unlock(ctx context.Context, id int, password string) error
+    Revisions(ctx context.Context, snippetID int) ([]Revision, error)
+    GetRevision(ctx context.Context, snippetID int, version int) (Revision, error)
 }
 
 // define a SnippetModel type which wraps a sql.DB connection pool
 type SnippetModel struct {
-    DB *sql.DB
+    DB           *sql.DB
+    // Dialect is the SQL dialect of DB. Blank means MySQL
+    Dialect      Dialect
+    // BcryptCost is the cost of passphrase hashes, as for UserModel
+    BcryptCost   int
+    // QueryTimeout is the most time a call may spend on the database.
+    // Zero means there is no limit beyond that of the context passed in
+    QueryTimeout time.Duration
 }
 
-// insert a new snippet into the database
-func (m *SnippetModel) Insert(title string, content string, expires int) (int, error) {
-    stmt := `INSERT INTO snippets (title, content, created, expires)
-    VALUES(?, ?, UTC_TIMESTAMP(), DATE_ADD(UTC_TIMESTAMP(), INTERVAL ? DAY))`
+// insert a new snippet into the database, owned by the given user, and
+// return the random slug that identifies it in URLs. A zero expiry means
+// the snippet never expires. The first revision of the snippet is
+// recorded in the same transaction
+func (m *SnippetModel) Insert(ctx context.Context, title string, content string, language string, visibility string, expires time.Time, burn bool, password string, userID int) (string, error) {
+    // an empty password leaves the snippet unlocked, which we store as a
+    // NULL hash. Otherwise the password is hashed just like a user's
+    var hashedPassword sql.NullString
+    if password != "" {
+        hash, err := bcrypt.GenerateFromPassword([]byte(password), m.BcryptCost)
+        if err != nil {
+            return "", err
+        }
+        hashedPassword = sql.NullString{String: string(hash), Valid: true}
+    }
+
+    // the deadline covers the database work, not the time spent hashing
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
 
-    result, err := m.DB.Exec(stmt, title, content, expires)
+    tx, err := m.DB.BeginTx(ctx, nil)
     if err != nil {
-        return 0, err
+        return "", err
+    }
+    // rollback is a no-op once the transaction has been committed
+    defer tx.Rollback()
+
+    stmt := `INSERT INTO snippets (slug, title, content, language, visibility, burn_after_reading, hashed_password, created, expires, user_id)
+    VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+    created := now()
+
+    var slug string
+    var id int
+
+    // slugs are random, so on the rare occasion that one is already taken
+    // the unique index rejects the insert and we try again with a new one.
+    // A failed statement aborts the whole transaction in PostgreSQL, so
+    // each attempt is made inside a savepoint that can be rolled back to
+    // on its own
+    for attempt := 0; ; attempt++ {
+        slug, err = newSlug()
+        if err != nil {
+            return "", err
+        }
+
+        _, err = tx.ExecContext(ctx, `SAVEPOINT insert_snippet`)
+        if err != nil {
+            return "", err
+        }
+
+        // insert() returns the id of our newly inserted record in the
+        // snippets table
+        id, err = m.Dialect.insert(ctx, tx, stmt, slug, title, content, language, visibility, burn, hashedPassword, created, nullableTime(expires), userID)
+        if err == nil {
+            break
+        }
+
+        if attempt < slugAttempts-1 && isDuplicate(err, snippetsSlugKey) {
+            _, err = tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT insert_snippet`)
+            if err != nil {
+                return "", err
+            }
+            continue
+        }
+
+        return "", err
     }
 
-    // use the LastInsertId() method on the result to get the ID
-    // of our newly inserted record in the snippets table
-    id, err := result.LastInsertId()
+    err = m.insertRevision(ctx, tx, id, created)
     if err != nil {
-        return 0, err
+        return "", err
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return "", err
     }
 
-    // ID is type int64, so needs to be converted before return
-    return int(id), nil 
+    return slug, nil
 }
 
 // return a specific snippet based on its id
-func (m *SnippetModel) Get(id int) (Snippet, error) {
-    stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() AND id = ?`
+func (m *SnippetModel) Get(ctx context.Context, id int) (Snippet, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
 
-    // use QueryRow() method on connection pool to execute the statement
-    row := m.DB.QueryRow(stmt, id)
+    // join on the users table so the author's name comes back with the snippet
+    stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > ?) AND s.burned IS NULL AND s.id = ?`
 
-    // initialize a new zeroed Snippet struct
-    var s Snippet
-    
-    // use row.Scan() to copy the values from each sql.Row to the 
-    // corresponding field in the Snippet struct. NOTICE the args to row.Scan 
-    // are *pointers* to the place we want to copy the data. 
-    // Number of args must be exactly the same as the columns returned by the statement
-    err := row.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
-    if err != nil {
-        // if the query returned no rows, then row.Scan() will return a
-        // sql.ErrNoRows error. Use the errors.Is() func check for that error
-        // and return our own ErrNoRecord error instead
+    // use QueryRow() method on connection pool to execute the statement,
+    // and scanSnippet() to copy the columns of the row into a Snippet.
+    // If the query returned no rows, then Scan() will return a
+    // sql.ErrNoRows error. Use the errors.Is() func check for that error
+    // and return our own ErrNoRecord error instead
+    s, err := scanSnippet(m.DB.QueryRowContext(ctx, m.Dialect.bind(stmt), now(), id))
+    if err != nil {
         if errors.Is(err, sql.ErrNoRows) {
             return Snippet{}, ErrNoRecord
         } else {
@@ -77,29 +178,429 @@ func (m *SnippetModel) Get(id int) (Snippet, error) {
     return s, nil
 }
 
-// return the 10 most recent snippets 
-func (m *SnippetModel) Latest() ([]Snippet, error) {
-    stmt := `SELECT id, title, content, created, expires FROM snippets
-    WHERE expires > UTC_TIMESTAMP() ORDER BY id DESC LIMIT 10`
+// return a specific snippet based on its slug
+func (m *SnippetModel) GetBySlug(ctx context.Context, slug string) (Snippet, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > ?) AND s.burned IS NULL AND s.slug = ?`
+
+    s, err := scanSnippet(m.DB.QueryRowContext(ctx, m.Dialect.bind(stmt), now(), slug))
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return Snippet{}, ErrNoRecord
+        } else {
+            return Snippet{}, err
+        }
+    }
+
+    return s, nil
+}
+
+// return the 10 most recent public snippets. Burn after reading snippets
+// are never listed, since showing them would give their content away 
+func (m *SnippetModel) Latest(ctx context.Context) ([]Snippet, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > ?) AND s.visibility = 'public' AND NOT s.burn_after_reading
+    ORDER BY s.id DESC LIMIT 10`
+
+    // querySnippets() runs the query, scans every row it returns and
+    // checks rows.Err() once the loop has finished
+    return querySnippets(ctx, m.DB, m.Dialect.bind(stmt), now())
+}
+
+// update the title, content and expiry of an existing snippet. A zero
+// expiry means the snippet never expires, just like on insert. The new
+// content is recorded as the next revision so earlier versions are kept.
+// If no snippet with the id exists we return ErrNoRecord. MySQL doesn't
+// count a row as affected by an UPDATE that leaves it as it was, so it is
+// the revision that tells us whether the snippet was there
+func (m *SnippetModel) Update(ctx context.Context, id int, title string, content string, language string, visibility string, expires time.Time) error {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
 
-    rows, err := m.DB.Query(stmt)
+    tx, err := m.DB.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback()
+
+    stmt := `UPDATE snippets SET title = ?, content = ?, language = ?, visibility = ?, expires = ?
+    WHERE id = ?`
+
+    _, err = tx.ExecContext(ctx, m.Dialect.bind(stmt), title, content, language, visibility, nullableTime(expires), id)
+    if err != nil {
+        return err
+    }
+
+    err = m.insertRevision(ctx, tx, id, now())
+    if err != nil {
+        return err
+    }
+
+    return tx.Commit()
+}
+
+// return a burn after reading snippet and destroy it in the same
+// transaction. The snippet is claimed by marking it as burned before it
+// is read, and only one transaction can make that change, so if two
+// requests try to burn the same snippet at once only one of them gets the
+// content; the other sees that no row was updated and gets ErrNoRecord.
+// The row itself is kept as a tombstone with its content cleared, and
+// its revisions are deleted since they hold copies of the content. The
+// tombstone expires when the snippet is burned, so that Purge() removes
+// it even if the snippet would otherwise never have expired
+func (m *SnippetModel) Burn(ctx context.Context, id int) (Snippet, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    tx, err := m.DB.BeginTx(ctx, nil)
+    if err != nil {
+        return Snippet{}, err
+    }
+    defer tx.Rollback()
+
+    burned := now()
+
+    stmt := `UPDATE snippets SET burned = ?
+    WHERE (expires IS NULL OR expires > ?) AND burned IS NULL AND burn_after_reading AND id = ?`
+
+    result, err := tx.ExecContext(ctx, m.Dialect.bind(stmt), burned, burned, id)
+    if err != nil {
+        return Snippet{}, err
+    }
+
+    rows, err := result.RowsAffected()
+    if err != nil {
+        return Snippet{}, err
+    }
+
+    if rows == 0 {
+        return Snippet{}, ErrNoRecord
+    }
+
+    stmt = `SELECT ` + snippetColumns + `
+    FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+    WHERE s.id = ?`
+
+    s, err := scanSnippet(tx.QueryRowContext(ctx, m.Dialect.bind(stmt), id))
+    if err != nil {
+        return Snippet{}, err
+    }
+
+    _, err = tx.ExecContext(ctx, m.Dialect.bind(`UPDATE snippets SET content = '', expires = ? WHERE id = ?`), burned, id)
+    if err != nil {
+        return Snippet{}, err
+    }
+
+    _, err = tx.ExecContext(ctx, m.Dialect.bind(`DELETE FROM snippet_revisions WHERE snippet_id = ?`), id)
+    if err != nil {
+        return Snippet{}, err
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return Snippet{}, err
+    }
+
+    return s, nil
+}
+
+// check the password of a locked snippet. If the snippet does not exist,
+// is not locked or the password is wrong we return ErrInvalidCredentials
+func (m *SnippetModel) Unlock(ctx context.Context, id int, password string) error {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    var hashedPassword []byte
+
+    stmt := `SELECT hashed_password FROM snippets
+    WHERE (expires IS NULL OR expires > ?) AND hashed_password IS NOT NULL AND id = ?`
+
+    err := m.DB.QueryRowContext(ctx, m.Dialect.bind(stmt), now(), id).Scan(&hashedPassword)
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return ErrInvalidCredentials
+        } else {
+            return err
+        }
+    }
+
+    err = bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+    if err != nil {
+        if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+            return ErrInvalidCredentials
+        } else {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// delete a snippet. If no snippet with the id exists we return ErrNoRecord
+func (m *SnippetModel) Delete(ctx context.Context, id int) error {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    stmt := `DELETE FROM snippets WHERE id = ?`
+
+    result, err := m.DB.ExecContext(ctx, m.Dialect.bind(stmt), id)
+    if err != nil {
+        return err
+    }
+
+    rows, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if rows == 0 {
+        return ErrNoRecord
+    }
+
+    return nil
+}
+
+// return a page of unexpired public snippets, newest first, using keyset
+// pagination on the id. If before is non-zero only snippets with a lower
+// id are returned, and if after is non-zero only snippets with a higher id
+// are returned, taking the limit snippets closest to the cursor
+func (m *SnippetModel) Archive(ctx context.Context, before int, after int, limit int) ([]Snippet, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > ?) AND s.visibility = 'public' AND NOT s.burn_after_reading AND (? = 0 OR s.id < ?)
+    ORDER BY s.id DESC LIMIT ?`
+    args := []any{now(), before, before, limit}
+
+    // when paging backwards we need the snippets just above the cursor,
+    // so walk up from it and reverse the result afterwards
+    if after > 0 {
+        stmt = `SELECT ` + snippetColumns + `
+        FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+        WHERE (s.expires IS NULL OR s.expires > ?) AND s.visibility = 'public' AND NOT s.burn_after_reading AND s.id > ?
+        ORDER BY s.id ASC LIMIT ?`
+        args = []any{now(), after, limit}
+    }
+
+    snippets, err := querySnippets(ctx, m.DB, m.Dialect.bind(stmt), args...)
     if err != nil {
         return nil, err
     }
 
-    // defer rows.Close() to ensrue the sql.Rows resultset is always properly
-    // closed before the Latest() method returns.
-    // This should ALWAYS come *after* you check for an error from the Query()
-    // otherwise you will get a panic if trying to close a nil resultset
+    if after > 0 {
+        slices.Reverse(snippets)
+    }
+
+    return snippets, nil
+}
+
+// the most words of a query that an SQLite search looks for, which keeps
+// the statement it builds to a reasonable size
+const sqliteSearchTerms = 10
+
+// return the unexpired public snippets matching a search on their title
+// and content, best matches first. Pages are numbered from 1. Locked snippets
+// are left out because the results include fragments of their content.
+// MySQL uses its full-text index. PostgreSQL finds the snippets containing
+// every word of the query, with the words stemmed so that "frogs" also
+// finds "frog". SQLite has no full-text index, so there a snippet matches
+// if it contains any of the words in the query, and those containing the
+// most words come first. LIKE ignores case for ASCII letters, much as
+// MySQL's full-text search does
+func (m *SnippetModel) Search(ctx context.Context, query string, page int, perPage int) ([]Snippet, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    // where picks out the snippets that match and rank orders them, each
+    // taking rankArgs
+    rank := `MATCH(s.title, s.content) AGAINST(? IN NATURAL LANGUAGE MODE)`
+    where := rank
+    rankArgs := []any{query}
+
+    switch m.Dialect {
+    case Postgres:
+        rank = `ts_rank(to_tsvector('english', s.title || ' ' || s.content), plainto_tsquery('english', ?))`
+        where = `to_tsvector('english', s.title || ' ' || s.content) @@ plainto_tsquery('english', ?)`
+    case SQLite:
+        terms := strings.Fields(query)
+        if len(terms) == 0 {
+            return nil, nil
+        }
+        if len(terms) > sqliteSearchTerms {
+            terms = terms[:sqliteSearchTerms]
+        }
+
+        // the rank counts how many of the terms a snippet contains
+        var likes []string
+        rankArgs = nil
+
+        for _, term := range terms {
+            pattern := "%" + escapeLike(term) + "%"
+            likes = append(likes, `(s.title LIKE ? ESCAPE '\' OR s.content LIKE ? ESCAPE '\')`)
+            rankArgs = append(rankArgs, pattern, pattern)
+        }
+
+        rank = strings.Join(likes, " + ")
+        where = rank + " > 0"
+    }
+
+    stmt := `SELECT ` + snippetColumns + `
+    FROM snippets s LEFT JOIN users u ON u.id = s.user_id
+    WHERE (s.expires IS NULL OR s.expires > ?) AND s.visibility = 'public' AND NOT s.burn_after_reading
+    AND s.hashed_password IS NULL
+    AND ` + where + `
+    ORDER BY ` + rank + ` DESC, s.id DESC
+    LIMIT ? OFFSET ?`
+
+    args := []any{now()}
+    args = append(args, rankArgs...)
+    args = append(args, rankArgs...)
+    args = append(args, perPage, (page-1)*perPage)
+
+    return querySnippets(ctx, m.DB, m.Dialect.bind(stmt), args...)
+}
+
+// escapeLike escapes the characters that have a special meaning in a LIKE
+// pattern, using backslash as the escape character
+func escapeLike(s string) string {
+    return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// permanently remove up to limit expired snippets, oldest first, and return
+// how many were removed. Their revisions go with them. If archive is true
+// the snippets are copied to the snippets_archive table before they are
+// deleted, in the same transaction
+func (m *SnippetModel) Purge(ctx context.Context, limit int, archive bool) (int, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    tx, err := m.DB.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
+    purged := now()
+
+    stmt := `SELECT id FROM snippets
+    WHERE expires IS NOT NULL AND expires <= ?
+    ORDER BY id LIMIT ?` + m.Dialect.forUpdate()
+
+    rows, err := tx.QueryContext(ctx, m.Dialect.bind(stmt), purged, limit)
+    if err != nil {
+        return 0, err
+    }
     defer rows.Close()
 
-    // initialize an empty slice of Snippet structs
-    var snippets []Snippet
+    var ids []any
 
     for rows.Next() {
-        var s Snippet
+        var id int
+
+        err := rows.Scan(&id)
+        if err != nil {
+            return 0, err
+        }
+
+        ids = append(ids, id)
+    }
 
-        err := rows.Scan(&s.ID, &s.Title, &s.Content, &s.Created, &s.Expires)
+    if err = rows.Err(); err != nil {
+        return 0, err
+    }
+
+    if len(ids) == 0 {
+        return 0, nil
+    }
+
+    in := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+    if archive {
+        stmt = `INSERT INTO snippets_archive (id, slug, title, content, created, expires, language, visibility, user_id, archived)
+        SELECT id, slug, title, content, created, expires, language, visibility, user_id, ?
+        FROM snippets WHERE id IN (` + in + `)`
+
+        _, err = tx.ExecContext(ctx, m.Dialect.bind(stmt), append([]any{purged}, ids...)...)
+        if err != nil {
+            return 0, err
+        }
+    }
+
+    _, err = tx.ExecContext(ctx, m.Dialect.bind(`DELETE FROM snippets WHERE id IN (`+in+`)`), ids...)
+    if err != nil {
+        return 0, err
+    }
+
+    err = tx.Commit()
+    if err != nil {
+        return 0, err
+    }
+
+    return len(ids), nil
+}
+
+// nullTime scans a nullable DATETIME column into a time.Time, which is
+// left as the zero time when the column is NULL
+type nullTime struct {
+    t *time.Time
+}
+
+func (n nullTime) Scan(value any) error {
+    var nt sql.NullTime
+
+    err := nt.Scan(value)
+    if err != nil {
+        return err
+    }
+
+    *n.t = nt.Time
+    return nil
+}
+
+// nullableTime converts a time.Time to a value for a nullable DATETIME
+// column, storing the zero time as NULL. Like now() it keeps the time to
+// the second in UTC
+func nullableTime(t time.Time) sql.NullTime {
+    return sql.NullTime{Time: t.UTC().Truncate(time.Second), Valid: !t.IsZero()}
+}
+
+// the columns read into a Snippet by scanSnippet(), which every snippet
+// model selects from snippets s left joined to users u. Snippets from
+// before snippets had owners have no user, so they get a UserID of 0
+const snippetColumns = `s.id, s.slug, s.title, s.content, s.created, s.expires, s.language, s.visibility, s.burn_after_reading, s.hashed_password IS NOT NULL, COALESCE(s.user_id, 0), COALESCE(u.name, 'anonymous')`
+
+// scanSnippet copies the snippetColumns of a row into a Snippet
+func scanSnippet(row interface{ Scan(...any) error }) (Snippet, error) {
+    var s Snippet
+
+    err := row.Scan(&s.ID, &s.Slug, &s.Title, &s.Content, &s.Created, nullTime{&s.Expires}, &s.Language, &s.Visibility, &s.BurnAfterReading, &s.Locked, &s.UserID, &s.UserName)
+
+    return s, err
+}
+
+// querySnippets runs a statement that selects snippetColumns and returns
+// the snippets it finds
+func querySnippets(ctx context.Context, db *sql.DB, stmt string, args ...any) ([]Snippet, error) {
+    rows, err := db.QueryContext(ctx, stmt, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var snippets []Snippet
+
+    for rows.Next() {
+        s, err := scanSnippet(rows)
         if err != nil {
             return nil, err
         }
@@ -107,13 +608,9 @@ func (m *SnippetModel) Latest() ([]Snippet, error) {
         snippets = append(snippets, s)
     }
 
-    // when loop has finished, we call rows.Err() to retrieve any error that 
-    // was encountered during the iteration. It is important to call this 
-    // DO NOT ASSUME that a successful iteration was completed over the whole resultset
     if err = rows.Err(); err != nil {
         return nil, err
     }
 
     return snippets, nil
-
 }
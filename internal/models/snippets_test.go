@@ -0,0 +1,272 @@
+package models
+
+import (
+    "context"
+    "errors"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+    "unicode"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestSnippetModelGetBySlugCase(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+
+    m := SnippetModel{DB: db, BcryptCost: 4}
+
+    ctx := context.Background()
+
+    slug, err := m.Insert(ctx, "An old silent pond", "An old silent pond...", "plaintext", VisibilityPublic, time.Now().Add(time.Hour), false, "", 1)
+    assert.NilError(t, err)
+
+    s, err := m.GetBySlug(ctx, slug)
+    assert.NilError(t, err)
+    assert.Equal(t, s.Slug, slug)
+
+    // slugs are case sensitive, so the same letters in the other case are
+    // a different slug
+    swapped := strings.Map(func(r rune) rune {
+        if unicode.IsUpper(r) {
+            return unicode.ToLower(r)
+        }
+        return unicode.ToUpper(r)
+    }, slug)
+
+    _, err = m.GetBySlug(ctx, swapped)
+    if !errors.Is(err, ErrNoRecord) {
+        t.Errorf("got %v; want %v", err, ErrNoRecord)
+    }
+}
+
+func TestSnippetModelUpdate(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+
+    m := SnippetModel{DB: db, BcryptCost: 4}
+
+    ctx := context.Background()
+
+    slug, err := m.Insert(ctx, "An old silent pond", "An old silent pond...", "plaintext", VisibilityPublic, time.Time{}, false, "", 1)
+    assert.NilError(t, err)
+
+    s, err := m.GetBySlug(ctx, slug)
+    assert.NilError(t, err)
+
+    // saving a snippet without changing it leaves MySQL with no affected
+    // rows, which must not be mistaken for a missing snippet
+    err = m.Update(ctx, s.ID, s.Title, s.Content, s.Language, s.Visibility, s.Expires)
+    assert.NilError(t, err)
+
+    err = m.Update(ctx, s.ID+1, s.Title, s.Content, s.Language, s.Visibility, s.Expires)
+    assert.Equal(t, err, ErrNoRecord)
+}
+
+func TestSnippetModelBurn(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+
+    m := SnippetModel{DB: db, BcryptCost: 4}
+
+    ctx := context.Background()
+
+    slug, err := m.Insert(ctx, "Secret", "Read me once", "plaintext", VisibilityUnlisted, time.Time{}, true, "", 1)
+    assert.NilError(t, err)
+
+    s, err := m.GetBySlug(ctx, slug)
+    assert.NilError(t, err)
+
+    // only the first Burn() to mark the row as burned can read it, so
+    // however many readers race for the snippet only one of them gets its
+    // content
+    readers := 8
+    contents := make(chan string, readers)
+
+    var wg sync.WaitGroup
+
+    for i := 0; i < readers; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+
+            burned, err := m.Burn(ctx, s.ID)
+            if err != nil {
+                if !errors.Is(err, ErrNoRecord) {
+                    t.Error(err)
+                }
+                return
+            }
+
+            contents <- burned.Content
+        }()
+    }
+
+    wg.Wait()
+    close(contents)
+
+    var got []string
+    for content := range contents {
+        got = append(got, content)
+    }
+
+    if len(got) != 1 {
+        t.Fatalf("got the content %d times; want 1", len(got))
+    }
+    assert.Equal(t, got[0], "Read me once")
+
+    _, err = m.GetBySlug(ctx, slug)
+    assert.Equal(t, err, ErrNoRecord)
+
+    // the tombstone keeps neither the content nor any revisions of it
+    var content string
+    err = db.QueryRow(`SELECT content FROM snippets WHERE id = ?`, s.ID).Scan(&content)
+    assert.NilError(t, err)
+    assert.Equal(t, content, "")
+
+    revisions, err := m.Revisions(ctx, s.ID)
+    assert.NilError(t, err)
+    assert.Equal(t, len(revisions), 0)
+
+    // the snippet never expired, but its tombstone does so that it is
+    // purged
+    n, err := m.Purge(ctx, 10, false)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 1)
+}
+
+func TestSnippetModelSearch(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+
+    m := SnippetModel{DB: db, BcryptCost: 4}
+
+    ctx := context.Background()
+
+    for _, title := range []string{"Frog in the pond", "Frog on a log", "Crow on a branch", "Still pond"} {
+        _, err := m.Insert(ctx, title, "", "plaintext", VisibilityPublic, time.Time{}, false, "", 1)
+        assert.NilError(t, err)
+    }
+
+    // none of these may turn up in search results
+    _, err := m.Insert(ctx, "Locked pond frog", "", "plaintext", VisibilityPublic, time.Time{}, false, "pa$$phrase", 1)
+    assert.NilError(t, err)
+    _, err = m.Insert(ctx, "Private pond frog", "", "plaintext", VisibilityPrivate, time.Time{}, false, "", 1)
+    assert.NilError(t, err)
+    _, err = m.Insert(ctx, "Burning pond frog", "", "plaintext", VisibilityPublic, time.Time{}, true, "", 1)
+    assert.NilError(t, err)
+
+    tests := []struct {
+        name    string
+        query   string
+        page    int
+        perPage int
+        first   string
+        count   int
+    }{
+        {
+            name:    "Best match first",
+            query:   "FROG pond",
+            page:    1,
+            perPage: 10,
+            first:   "Frog in the pond",
+            count:   3,
+        },
+        {
+            name:    "Second page",
+            query:   "frog pond",
+            page:    2,
+            perPage: 2,
+            count:   1,
+        },
+        {
+            name:    "No match",
+            query:   "heron",
+            page:    1,
+            perPage: 10,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            snippets, err := m.Search(ctx, tt.query, tt.page, tt.perPage)
+            assert.NilError(t, err)
+            assert.Equal(t, len(snippets), tt.count)
+
+            if tt.first != "" && len(snippets) > 0 {
+                assert.Equal(t, snippets[0].Title, tt.first)
+            }
+        })
+    }
+}
+
+func TestSnippetModelPurge(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    db := newTestDB(t)
+
+    m := SnippetModel{DB: db, BcryptCost: 4}
+
+    ctx := context.Background()
+
+    for i := 0; i < 3; i++ {
+        _, err := m.Insert(ctx, "Expiring", "Soon gone", "plaintext", VisibilityPublic, time.Now().Add(time.Hour), false, "", 1)
+        assert.NilError(t, err)
+    }
+    _, err := m.Insert(ctx, "Lasting", "Here to stay", "plaintext", VisibilityPublic, time.Time{}, false, "", 1)
+    assert.NilError(t, err)
+
+    // a snippet from before snippets had owners, which is archived too
+    _, err = db.Exec(`INSERT INTO snippets (slug, title, content, created, expires) VALUES ('aged234567', 'Expiring', 'Soon gone', UTC_TIMESTAMP(), UTC_TIMESTAMP())`)
+    assert.NilError(t, err)
+
+    // move the expiring snippets into the past
+    _, err = db.Exec(`UPDATE snippets SET expires = ? WHERE title = 'Expiring'`, time.Now().UTC().Add(-time.Hour).Truncate(time.Second))
+    assert.NilError(t, err)
+
+    // each call removes at most limit snippets, oldest first
+    n, err := m.Purge(ctx, 2, true)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 2)
+
+    n, err = m.Purge(ctx, 2, true)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 2)
+
+    n, err = m.Purge(ctx, 2, true)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 0)
+
+    var archived, owned int
+    err = db.QueryRow(`SELECT COUNT(*), COUNT(user_id) FROM snippets_archive WHERE title = 'Expiring'`).Scan(&archived, &owned)
+    assert.NilError(t, err)
+    assert.Equal(t, archived, 4)
+    assert.Equal(t, owned, 3)
+
+    // the revisions of the purged snippets go with them
+    var revisions int
+    err = db.QueryRow(`SELECT COUNT(*) FROM snippet_revisions r INNER JOIN snippets_archive a ON a.id = r.snippet_id`).Scan(&revisions)
+    assert.NilError(t, err)
+    assert.Equal(t, revisions, 0)
+
+    latest, err := m.Latest(ctx)
+    assert.NilError(t, err)
+    assert.Equal(t, len(latest), 1)
+    assert.Equal(t, latest[0].Title, "Lasting")
+}
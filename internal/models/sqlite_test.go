@@ -0,0 +1,352 @@
+package models
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "testing"
+    "time"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+    "github.com/j-clemons/snippetbox/internal/migrations"
+
+    "github.com/lib/pq"
+)
+
+// the SQLite tests run against an in-memory database, so unlike the MySQL
+// tests they are not skipped in short mode
+
+func TestSQLiteUserModel(t *testing.T) {
+    db := newSQLiteTestDB(t)
+    m := UserModel{DB: db, Dialect: SQLite, BcryptCost: 4}
+    ctx := context.Background()
+
+    exists, err := m.Exists(ctx, 1)
+    assert.NilError(t, err)
+    assert.Equal(t, exists, true)
+
+    exists, err = m.Exists(ctx, 2)
+    assert.NilError(t, err)
+    assert.Equal(t, exists, false)
+
+    err = m.Insert(ctx, "Bob", "bob@example.com", "pa$$word")
+    assert.NilError(t, err)
+
+    err = m.Insert(ctx, "Bobby", "bob@example.com", "pa$$word")
+    assert.Equal(t, err, ErrDuplicateEmail)
+
+    id, err := m.Authenticate(ctx, "bob@example.com", "pa$$word")
+    assert.NilError(t, err)
+    assert.Equal(t, id, 2)
+
+    _, err = m.Authenticate(ctx, "bob@example.com", "wrong")
+    assert.Equal(t, err, ErrInvalidCredentials)
+
+    _, err = m.Authenticate(ctx, "nobody@example.com", "pa$$word")
+    assert.Equal(t, err, ErrInvalidCredentials)
+}
+
+func TestSQLiteSnippetModel(t *testing.T) {
+    db := newSQLiteTestDB(t)
+    m := SnippetModel{DB: db, Dialect: SQLite, BcryptCost: 4}
+    ctx := context.Background()
+
+    expires := time.Now().Add(24 * time.Hour)
+
+    slug, err := m.Insert(ctx, "An old silent pond", "A frog jumps into the pond", "plaintext", VisibilityPublic, expires, false, "", 1)
+    assert.NilError(t, err)
+    assert.Equal(t, SlugRX.MatchString(slug), true)
+
+    s, err := m.GetBySlug(ctx, slug)
+    assert.NilError(t, err)
+    assert.Equal(t, s.Title, "An old silent pond")
+    assert.Equal(t, s.UserName, "Alice Jones")
+    assert.Equal(t, s.Expires.Equal(expires.UTC().Truncate(time.Second)), true)
+    assert.Equal(t, s.Locked, false)
+
+    err = m.Update(ctx, s.ID, "Over the wintry forest", "Winds howl in rage", "plaintext", VisibilityPublic, time.Time{})
+    assert.NilError(t, err)
+
+    err = m.Update(ctx, s.ID+1, "Over the wintry forest", "Winds howl in rage", "plaintext", VisibilityPublic, time.Time{})
+    assert.Equal(t, err, ErrNoRecord)
+
+    s, err = m.Get(ctx, s.ID)
+    assert.NilError(t, err)
+    assert.Equal(t, s.Title, "Over the wintry forest")
+    assert.Equal(t, s.Expires.IsZero(), true)
+
+    revisions, err := m.Revisions(ctx, s.ID)
+    assert.NilError(t, err)
+    assert.Equal(t, len(revisions), 2)
+    assert.Equal(t, revisions[0].Version, 2)
+
+    r, err := m.GetRevision(ctx, s.ID, 1)
+    assert.NilError(t, err)
+    assert.Equal(t, r.Title, "An old silent pond")
+
+    latest, err := m.Latest(ctx)
+    assert.NilError(t, err)
+    assert.Equal(t, len(latest), 1)
+
+    err = m.Delete(ctx, s.ID)
+    assert.NilError(t, err)
+
+    _, err = m.Get(ctx, s.ID)
+    assert.Equal(t, err, ErrNoRecord)
+
+    // the revisions go with the snippet
+    revisions, err = m.Revisions(ctx, s.ID)
+    assert.NilError(t, err)
+    assert.Equal(t, len(revisions), 0)
+
+    err = m.Delete(ctx, s.ID)
+    assert.Equal(t, err, ErrNoRecord)
+}
+
+func TestSQLiteRevisionsBackfill(t *testing.T) {
+    db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+    if err != nil {
+        t.Fatal(err)
+    }
+    db.SetMaxOpenConns(1)
+    t.Cleanup(func() { db.Close() })
+
+    // stop short of the migration that adds revisions, and create the
+    // snippets the way the application did before it
+    mg, err := migrations.New(db, migrations.SQLite)
+    if err != nil {
+        t.Fatal(err)
+    }
+    all := mg.Migrations
+    mg.Migrations = all[:11]
+
+    _, err = mg.Up()
+    assert.NilError(t, err)
+
+    _, err = db.Exec(`INSERT INTO users (name, email, hashed_password, created) VALUES ('Alice Jones', 'alice@example.com', '', '2022-01-01 09:18:24')`)
+    assert.NilError(t, err)
+    _, err = db.Exec(`INSERT INTO snippets (slug, title, content, created, user_id) VALUES ('owned23456', 'An old silent pond', 'A frog jumps into the pond', '2022-01-01 10:00:00', 1)`)
+    assert.NilError(t, err)
+    _, err = db.Exec(`INSERT INTO snippets (slug, title, content, created) VALUES ('aged234567', 'Nobody owns me', 'Anonymous', '2022-01-01 10:00:00')`)
+    assert.NilError(t, err)
+
+    mg.Migrations = all
+
+    _, err = mg.Up()
+    assert.NilError(t, err)
+
+    m := SnippetModel{DB: db, Dialect: SQLite, BcryptCost: 4}
+    ctx := context.Background()
+
+    s, err := m.GetBySlug(ctx, "owned23456")
+    assert.NilError(t, err)
+
+    err = m.Update(ctx, s.ID, "Over the wintry forest", "Winds howl in rage", "plaintext", VisibilityPublic, time.Time{})
+    assert.NilError(t, err)
+
+    // the text from before the migration survives the edit as version 1
+    revisions, err := m.Revisions(ctx, s.ID)
+    assert.NilError(t, err)
+    assert.Equal(t, len(revisions), 2)
+
+    r, err := m.GetRevision(ctx, s.ID, 1)
+    assert.NilError(t, err)
+    assert.Equal(t, r.Title, "An old silent pond")
+    assert.Equal(t, r.Content, "A frog jumps into the pond")
+
+    // snippets without an owner are left without a history
+    s, err = m.GetBySlug(ctx, "aged234567")
+    assert.NilError(t, err)
+
+    revisions, err = m.Revisions(ctx, s.ID)
+    assert.NilError(t, err)
+    assert.Equal(t, len(revisions), 0)
+}
+
+func TestSQLiteSnippetModelBurn(t *testing.T) {
+    db := newSQLiteTestDB(t)
+    m := SnippetModel{DB: db, Dialect: SQLite, BcryptCost: 4}
+    ctx := context.Background()
+
+    slug, err := m.Insert(ctx, "Secret", "Read me once", "plaintext", VisibilityUnlisted, time.Time{}, true, "", 1)
+    assert.NilError(t, err)
+
+    s, err := m.GetBySlug(ctx, slug)
+    assert.NilError(t, err)
+
+    burned, err := m.Burn(ctx, s.ID)
+    assert.NilError(t, err)
+    assert.Equal(t, burned.Content, "Read me once")
+
+    _, err = m.Burn(ctx, s.ID)
+    assert.Equal(t, err, ErrNoRecord)
+
+    _, err = m.GetBySlug(ctx, slug)
+    assert.Equal(t, err, ErrNoRecord)
+
+    // the snippet never expired, but its tombstone does so that it is
+    // purged
+    n, err := m.Purge(ctx, 10, false)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 1)
+}
+
+func TestSQLiteSnippetModelUnlock(t *testing.T) {
+    db := newSQLiteTestDB(t)
+    m := SnippetModel{DB: db, Dialect: SQLite, BcryptCost: 4}
+    ctx := context.Background()
+
+    slug, err := m.Insert(ctx, "Locked", "Behind a passphrase", "plaintext", VisibilityPublic, time.Time{}, false, "pa$$phrase", 1)
+    assert.NilError(t, err)
+
+    s, err := m.GetBySlug(ctx, slug)
+    assert.NilError(t, err)
+    assert.Equal(t, s.Locked, true)
+
+    assert.NilError(t, m.Unlock(ctx, s.ID, "pa$$phrase"))
+    assert.Equal(t, m.Unlock(ctx, s.ID, "wrong"), ErrInvalidCredentials)
+    assert.Equal(t, m.Unlock(ctx, s.ID+1, "pa$$phrase"), ErrInvalidCredentials)
+}
+
+func TestSQLiteSnippetModelSearch(t *testing.T) {
+    db := newSQLiteTestDB(t)
+    m := SnippetModel{DB: db, Dialect: SQLite, BcryptCost: 4}
+    ctx := context.Background()
+
+    for _, title := range []string{"Frog in the pond", "Frog on a log", "Crow on a branch", "100% pond"} {
+        _, err := m.Insert(ctx, title, "", "plaintext", VisibilityPublic, time.Time{}, false, "", 1)
+        assert.NilError(t, err)
+    }
+    _, err := m.Insert(ctx, "Locked pond frog", "", "plaintext", VisibilityPublic, time.Time{}, false, "pa$$phrase", 1)
+    assert.NilError(t, err)
+
+    tests := []struct {
+        name  string
+        query string
+        want  []string
+    }{
+        {
+            name:  "Best match first",
+            query: "FROG pond",
+            want:  []string{"Frog in the pond", "100% pond", "Frog on a log"},
+        },
+        {
+            name:  "Wildcards are literal",
+            query: "%",
+            want:  []string{"100% pond"},
+        },
+        {
+            name:  "No match",
+            query: "heron",
+        },
+        {
+            name:  "Blank",
+            query: "  ",
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            snippets, err := m.Search(ctx, tt.query, 1, 10)
+            assert.NilError(t, err)
+
+            var titles []string
+            for _, s := range snippets {
+                titles = append(titles, s.Title)
+            }
+
+            assert.Equal(t, len(titles), len(tt.want))
+            for i := range tt.want {
+                assert.Equal(t, titles[i], tt.want[i])
+            }
+        })
+    }
+}
+
+func TestSQLiteSnippetModelPurge(t *testing.T) {
+    db := newSQLiteTestDB(t)
+    m := SnippetModel{DB: db, Dialect: SQLite, BcryptCost: 4}
+    ctx := context.Background()
+
+    for i := 0; i < 3; i++ {
+        _, err := m.Insert(ctx, "Expiring", "Soon gone", "plaintext", VisibilityPublic, time.Now().Add(time.Hour), false, "", 1)
+        assert.NilError(t, err)
+    }
+    _, err := m.Insert(ctx, "Lasting", "Here to stay", "plaintext", VisibilityPublic, time.Time{}, false, "", 1)
+    assert.NilError(t, err)
+
+    // move the expiring snippets into the past
+    _, err = db.Exec(`UPDATE snippets SET expires = ? WHERE title = 'Expiring'`, time.Now().UTC().Add(-time.Hour).Truncate(time.Second))
+    assert.NilError(t, err)
+
+    n, err := m.Purge(ctx, 2, true)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 2)
+
+    n, err = m.Purge(ctx, 2, false)
+    assert.NilError(t, err)
+    assert.Equal(t, n, 1)
+
+    var archived int
+    err = db.QueryRow(`SELECT COUNT(*) FROM snippets_archive`).Scan(&archived)
+    assert.NilError(t, err)
+    assert.Equal(t, archived, 2)
+
+    latest, err := m.Latest(ctx)
+    assert.NilError(t, err)
+    assert.Equal(t, len(latest), 1)
+    assert.Equal(t, latest[0].Title, "Lasting")
+}
+
+func TestSQLiteTokenModel(t *testing.T) {
+    testTokenModel(t, &TokenModel{DB: newSQLiteTestDB(t), Dialect: SQLite})
+}
+
+func TestSQLiteQueryTimeout(t *testing.T) {
+    db := newSQLiteTestDB(t)
+
+    // a deadline in the past has always expired by the time the query runs
+    expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+    defer cancel()
+
+    m := UserModel{DB: db, Dialect: SQLite}
+
+    _, err := m.Exists(expired, 1)
+    assert.Equal(t, IsTimeout(err), true)
+
+    // the model's own timeout applies as well as the context's
+    m.QueryTimeout = time.Nanosecond
+
+    _, err = m.Exists(context.Background(), 1)
+    assert.Equal(t, IsTimeout(err), true)
+
+    m.QueryTimeout = time.Minute
+
+    exists, err := m.Exists(context.Background(), 1)
+    assert.NilError(t, err)
+    assert.Equal(t, exists, true)
+}
+
+func TestIsTimeout(t *testing.T) {
+    assert.Equal(t, IsTimeout(context.DeadlineExceeded), true)
+    assert.Equal(t, IsTimeout(fmt.Errorf("query: %w", context.DeadlineExceeded)), true)
+    assert.Equal(t, IsTimeout(&pq.Error{Code: "57014"}), true)
+    assert.Equal(t, IsTimeout(&pq.Error{Code: "23505"}), false)
+    assert.Equal(t, IsTimeout(ErrNoRecord), false)
+}
+
+func TestIsDuplicate(t *testing.T) {
+    db := newSQLiteTestDB(t)
+
+    _, err := db.Exec(`INSERT INTO users (name, email, hashed_password, created) VALUES ('Alice', 'alice@example.com', '', '2022-01-01 09:18:24')`)
+    assert.Equal(t, isDuplicate(err, usersEmailKey), true)
+    assert.Equal(t, isDuplicate(err, snippetsSlugKey), false)
+
+    err = &pq.Error{Code: "23505", Constraint: "users_uc_email"}
+    assert.Equal(t, isDuplicate(err, usersEmailKey), true)
+    assert.Equal(t, isDuplicate(err, snippetsSlugKey), false)
+
+    assert.Equal(t, isDuplicate(errors.New("users.email"), usersEmailKey), false)
+    assert.Equal(t, isDuplicate(nil, usersEmailKey), false)
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "github.com/lib/pq"
+)
+
+// withTimeout returns a context that is cancelled once timeout has passed,
+// or when ctx is. A timeout of zero adds no deadline of its own
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+    if timeout <= 0 {
+        return context.WithCancel(ctx)
+    }
+
+    return context.WithTimeout(ctx, timeout)
+}
+
+// IsTimeout reports whether err means that the database took too long.
+// The MySQL and SQLite drivers return the context's error when its deadline
+// passes, while PostgreSQL reports that the statement was cancelled
+func IsTimeout(err error) bool {
+    if errors.Is(err, context.DeadlineExceeded) {
+        return true
+    }
+
+    var pqError *pq.Error
+    return errors.As(err, &pqError) && pqError.Code == "57014"
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+    "context"
+    "crypto/sha256"
+    "database/sql"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "regexp"
+    "time"
+)
+
+// the scopes an API token can have. Read tokens can only fetch snippets,
+// while write tokens can also create, change and delete them
+const (
+    ScopeRead  = "read"
+    ScopeWrite = "write"
+)
+
+// tokenPrefix starts every API token, so that tokens are easy to recognise,
+// for instance by secret scanners, if they turn up somewhere they should not
+const tokenPrefix = "sbx_"
+
+// tokenLength is the number of random characters after tokenPrefix. That
+// gives roughly 2^233 tokens, so they cannot be guessed
+const tokenLength = 40
+
+// tokenPrefixLength is how much of a token is kept in the clear, so that
+// people can tell their tokens apart
+const tokenPrefixLength = len(tokenPrefix) + 8
+
+// lastUsedInterval is how often the time a token was last used is updated.
+// A token used in a loop would otherwise write to the database every time
+const lastUsedInterval = time.Minute
+
+// TokenRX matches strings that have the form of an API token
+var TokenRX = regexp.MustCompile(fmt.Sprintf("^%s[%s]{%d}$", tokenPrefix, slugAlphabet, tokenLength))
+
+// Token is a personal API token. Only a hash of the token itself is
+// stored, so after it has been created it is known by its Prefix
+type Token struct {
+    ID       int
+    UserID   int
+    Name     string
+    Prefix   string
+    Scope    string
+    Created  time.Time
+    // LastUsed is the zero time for tokens that have never been used
+    LastUsed time.Time
+}
+
+type TokenModelInterface interface {
+    Insert(ctx context.Context, userID int, name string, scope string) (string, error)
+    Authenticate(ctx context.Context, token string) (Token, error)
+    List(ctx context.Context, userID int) ([]Token, error)
+    Delete(ctx context.Context, id int, userID int) error
+}
+
+type TokenModel struct {
+    DB           *sql.DB
+    // Dialect is the SQL dialect of DB. Blank means MySQL
+    Dialect      Dialect
+    // QueryTimeout is the most time a call may spend on the database.
+    // Zero means there is no limit beyond that of the context passed in
+    QueryTimeout time.Duration
+}
+
+// Insert creates a token for the given user and returns it. This is the
+// only time the token itself is available, since just its hash is stored
+func (m *TokenModel) Insert(ctx context.Context, userID int, name string, scope string) (string, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    token, err := newToken()
+    if err != nil {
+        return "", err
+    }
+
+    stmt := `INSERT INTO api_tokens (user_id, name, prefix, hashed_token, scope, created)
+    VALUES(?, ?, ?, ?, ?, ?)`
+
+    _, err = m.DB.ExecContext(ctx, m.Dialect.bind(stmt), userID, name, token[:tokenPrefixLength], hashToken(token), scope, now())
+    if err != nil {
+        return "", err
+    }
+
+    return token, nil
+}
+
+// Authenticate returns the token matching the one given, recording that
+// it has been used. ErrInvalidCredentials means there is no such token
+func (m *TokenModel) Authenticate(ctx context.Context, token string) (Token, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    stmt := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE hashed_token = ?`
+
+    t, err := scanToken(m.DB.QueryRowContext(ctx, m.Dialect.bind(stmt), hashToken(token)))
+    if err != nil {
+        if errors.Is(err, sql.ErrNoRows) {
+            return Token{}, ErrInvalidCredentials
+        }
+        return Token{}, err
+    }
+
+    if time.Since(t.LastUsed) > lastUsedInterval {
+        _, err = m.DB.ExecContext(ctx, m.Dialect.bind(`UPDATE api_tokens SET last_used = ? WHERE id = ?`), now(), t.ID)
+        if err != nil {
+            return Token{}, err
+        }
+    }
+
+    return t, nil
+}
+
+// List returns the tokens of a user, newest first
+func (m *TokenModel) List(ctx context.Context, userID int) ([]Token, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    stmt := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE user_id = ? ORDER BY id DESC`
+
+    return queryTokens(ctx, m.DB, m.Dialect.bind(stmt), userID)
+}
+
+// Delete revokes a token. Users can only revoke their own tokens, so
+// ErrNoRecord is returned if the token belongs to someone else
+func (m *TokenModel) Delete(ctx context.Context, id int, userID int) error {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
+    return deleteToken(ctx, m.DB, m.Dialect.bind(`DELETE FROM api_tokens WHERE id = ? AND user_id = ?`), id, userID)
+}
+
+// newToken returns a new random API token
+func newToken() (string, error) {
+    s, err := randomString(tokenLength)
+    if err != nil {
+        return "", err
+    }
+
+    return tokenPrefix + s, nil
+}
+
+// hashToken returns the hash of a token that is stored in its place. The
+// tokens are long and random, so unlike passwords they do not need a slow
+// hash; a fast one means they can be looked up by their hash
+func hashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+// the columns read into a Token by scanToken()
+const tokenColumns = `id, user_id, name, prefix, scope, created, last_used`
+
+// scanToken copies the tokenColumns of a row into a Token
+func scanToken(row interface{ Scan(...any) error }) (Token, error) {
+    var t Token
+
+    err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &t.Scope, &t.Created, nullTime{&t.LastUsed})
+    return t, err
+}
+
+// queryTokens runs a query for tokenColumns and returns the tokens found
+func queryTokens(ctx context.Context, db *sql.DB, stmt string, args ...any) ([]Token, error) {
+    rows, err := db.QueryContext(ctx, stmt, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var tokens []Token
+
+    for rows.Next() {
+        t, err := scanToken(rows)
+        if err != nil {
+            return nil, err
+        }
+        tokens = append(tokens, t)
+    }
+
+    if err = rows.Err(); err != nil {
+        return nil, err
+    }
+
+    return tokens, nil
+}
+
+// deleteToken runs a DELETE statement for a single token, returning
+// ErrNoRecord if nothing was deleted
+func deleteToken(ctx context.Context, db *sql.DB, stmt string, args ...any) error {
+    result, err := db.ExecContext(ctx, stmt, args...)
+    if err != nil {
+        return err
+    }
+
+    n, err := result.RowsAffected()
+    if err != nil {
+        return err
+    }
+
+    if n == 0 {
+        return ErrNoRecord
+    }
+
+    return nil
+}
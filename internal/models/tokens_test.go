@@ -0,0 +1,72 @@
+package models
+
+import (
+    "context"
+    "strings"
+    "testing"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+func TestNewToken(t *testing.T) {
+    seen := make(map[string]bool)
+
+    for i := 0; i < 1000; i++ {
+        token, err := newToken()
+        assert.NilError(t, err)
+
+        assert.Equal(t, TokenRX.MatchString(token), true)
+        assert.Equal(t, seen[token], false)
+
+        seen[token] = true
+    }
+}
+
+func TestTokenModel(t *testing.T) {
+    if testing.Short() {
+        t.Skip("models: skipping integration test")
+    }
+
+    testTokenModel(t, &TokenModel{DB: newTestDB(t)})
+}
+
+// testTokenModel runs the same checks against each implementation of
+// TokenModelInterface, given a database holding the test data
+func testTokenModel(t *testing.T, m TokenModelInterface) {
+    ctx := context.Background()
+
+    token, err := m.Insert(ctx, 1, "Laptop", ScopeWrite)
+    assert.NilError(t, err)
+    assert.Equal(t, TokenRX.MatchString(token), true)
+
+    _, err = m.Insert(ctx, 1, "CI", ScopeRead)
+    assert.NilError(t, err)
+
+    tokens, err := m.List(ctx, 1)
+    assert.NilError(t, err)
+    assert.Equal(t, len(tokens), 2)
+    assert.Equal(t, tokens[0].Name, "CI")
+    assert.Equal(t, tokens[1].Prefix, token[:tokenPrefixLength])
+    assert.Equal(t, tokens[1].LastUsed.IsZero(), true)
+
+    found, err := m.Authenticate(ctx, token)
+    assert.NilError(t, err)
+    assert.Equal(t, found.UserID, 1)
+    assert.Equal(t, found.Scope, ScopeWrite)
+
+    tokens, err = m.List(ctx, 1)
+    assert.NilError(t, err)
+    assert.Equal(t, tokens[1].LastUsed.IsZero(), false)
+
+    _, err = m.Authenticate(ctx, strings.ToUpper(token))
+    assert.Equal(t, err, ErrInvalidCredentials)
+
+    // nobody else can revoke the token
+    assert.Equal(t, m.Delete(ctx, found.ID, 2), ErrNoRecord)
+
+    assert.NilError(t, m.Delete(ctx, found.ID, 1))
+    assert.Equal(t, m.Delete(ctx, found.ID, 1), ErrNoRecord)
+
+    _, err = m.Authenticate(ctx, token)
+    assert.Equal(t, err, ErrInvalidCredentials)
+}
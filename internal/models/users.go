@@ -1,19 +1,18 @@
 package models
 
 import (
+    "context"
     "database/sql"
     "errors"
-    "strings"
     "time"
 
-    "github.com/go-sql-driver/mysql"
     "golang.org/x/crypto/bcrypt"
 )
 
 type UserModelInterface interface {
-    Insert(name, email, password string) error
-    Authenticate(email, password string) (int, error)
-    Exists(id int) (bool, error)
+    Insert(ctx context.Context, name, email, password string) error
+    Authenticate(ctx context.Context, email, password string) (int, error)
+    Exists(ctx context.Context, id int) (bool, error)
 }
 
 type User struct {
@@ -25,28 +24,36 @@ type User struct {
 }
 
 type UserModel struct {
-    DB *sql.DB
+    DB           *sql.DB
+    // Dialect is the SQL dialect of DB. Blank means MySQL
+    Dialect      Dialect
+    // BcryptCost is the cost of password hashes. Anything below
+    // bcrypt.MinCost, such as zero, means bcrypt.DefaultCost
+    BcryptCost   int
+    // QueryTimeout is the most time a call may spend on the database.
+    // Zero means there is no limit beyond that of the context passed in
+    QueryTimeout time.Duration
 }
 
-func (m *UserModel) Insert(name, email, password string) error {
-    HashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+func (m *UserModel) Insert(ctx context.Context, name, email, password string) error {
+    HashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), m.BcryptCost)
     if err != nil {
         return err
     }
 
+    // the deadline covers the database work, not the time spent hashing
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
     stmt := `INSERT INTO users (name, email, hashed_password, created)
-    VALUES(?, ?, ?, UTC_TIMESTAMP())`
+    VALUES(?, ?, ?, ?)`
 
-    _, err = m.DB.Exec(stmt, name, email, string(HashedPassword))
+    _, err = m.DB.ExecContext(ctx, m.Dialect.bind(stmt), name, email, string(HashedPassword), now())
     if err != nil {
-        // if returns an error, we use the errors.As() func to check
-        // if the error has the type *mysql.MySQLError
-        // if yes then check for duplicate email
-        var mySQLError *mysql.MySQLError
-        if errors.As(err, &mySQLError) {
-            if mySQLError.Number == 1062 && strings.Contains(mySQLError.Message, "users_uc_email") {
-                return ErrDuplicateEmail
-            }
+        // if the email is already taken the unique constraint on it
+        // rejects the insert, which we report as ErrDuplicateEmail
+        if isDuplicate(err, usersEmailKey) {
+            return ErrDuplicateEmail
         }
         return err
     }
@@ -54,13 +61,16 @@ func (m *UserModel) Insert(name, email, password string) error {
     return nil
 }
 
-func (m *UserModel) Authenticate(email, password string) (int, error) {
+func (m *UserModel) Authenticate(ctx context.Context, email, password string) (int, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
     var id int
     var hashedPassword []byte
 
     stmt := "SELECT id, hashed_password FROM users WHERE email = ?"
 
-    err := m.DB.QueryRow(stmt, email).Scan(&id, &hashedPassword)
+    err := m.DB.QueryRowContext(ctx, m.Dialect.bind(stmt), email).Scan(&id, &hashedPassword)
     if err != nil {
         if errors.Is(err, sql.ErrNoRows) {
             return 0, ErrInvalidCredentials
@@ -78,14 +88,17 @@ func (m *UserModel) Authenticate(email, password string) (int, error) {
         }
     }
 
-    return 0, nil
+    return id, nil
 }
 
-func (m *UserModel) Exists(id int) (bool, error) {
+func (m *UserModel) Exists(ctx context.Context, id int) (bool, error) {
+    ctx, cancel := withTimeout(ctx, m.QueryTimeout)
+    defer cancel()
+
     var exists bool
 
     stmt := "SELECT EXISTS(SELECT true FROM users WHERE id = ?)"
 
-    err := m.DB.QueryRow(stmt, id).Scan(&exists)
+    err := m.DB.QueryRowContext(ctx, m.Dialect.bind(stmt), id).Scan(&exists)
     return exists, err
 }
@@ -1,6 +1,7 @@
 package models
 
 import (
+    "context"
     "testing"
 
     "github.com/j-clemons/snippetbox/internal/assert"
@@ -37,9 +38,11 @@ func TestUserModelExists(t *testing.T) {
         t.Run(tt.name, func(t *testing.T) {
             db := newTestDB(t)
 
-            m := UserModel{db}
+            m := UserModel{DB: db}
 
-            exists, err := m.Exists(tt.userID)
+            ctx := context.Background()
+
+            exists, err := m.Exists(ctx, tt.userID)
 
             assert.Equal(t, exists, tt.want)
             assert.NilError(t, err)
@@ -0,0 +1,152 @@
+package syntax
+
+import (
+    "bytes"
+    "encoding/json"
+    "html/template"
+    "io"
+    "strings"
+
+    "github.com/alecthomas/chroma/v2"
+    "github.com/alecthomas/chroma/v2/formatters/html"
+    "github.com/alecthomas/chroma/v2/lexers"
+    "github.com/alecthomas/chroma/v2/styles"
+)
+
+// Plaintext is the language used for snippets that are not code, or whose
+// language could not be detected
+const Plaintext = "plaintext"
+
+// Markdown is the language of snippets that are notes rather than code.
+// They are rendered to HTML instead of being highlighted
+const Markdown = "markdown"
+
+// Language describes a language that snippets can be highlighted as. Name
+// is the value stored in the database, Label is shown to users and
+// Extension is used for the filename when a snippet is downloaded
+type Language struct {
+    Name      string
+    Label     string
+    Extension string
+}
+
+// Languages lists every language a snippet may be saved with, in the order
+// they are offered in the create form
+var Languages = []Language{
+    {Plaintext, "Plain text", ".txt"},
+    {"bash", "Bash", ".sh"},
+    {"c", "C", ".c"},
+    {"css", "CSS", ".css"},
+    {"go", "Go", ".go"},
+    {"html", "HTML", ".html"},
+    {"java", "Java", ".java"},
+    {"javascript", "JavaScript", ".js"},
+    {"json", "JSON", ".json"},
+    {Markdown, "Markdown", ".md"},
+    {"python", "Python", ".py"},
+    {"ruby", "Ruby", ".rb"},
+    {"rust", "Rust", ".rs"},
+    {"sql", "SQL", ".sql"},
+    {"typescript", "TypeScript", ".ts"},
+    {"yaml", "YAML", ".yaml"},
+}
+
+// Names returns the name of every supported language, which is handy for
+// validating user input
+func Names() []string {
+    names := make([]string, len(Languages))
+    for i, l := range Languages {
+        names[i] = l.Name
+    }
+
+    return names
+}
+
+// Label returns the human readable label for a language name
+func Label(name string) string {
+    for _, l := range Languages {
+        if l.Name == name {
+            return l.Label
+        }
+    }
+
+    return name
+}
+
+// Extension returns the file extension, including the leading dot, for a
+// language name. Unknown languages are treated as plain text
+func Extension(name string) string {
+    for _, l := range Languages {
+        if l.Name == name {
+            return l.Extension
+        }
+    }
+
+    return ".txt"
+}
+
+// Detect guesses the language of content. Only supported languages are
+// ever returned; anything else is treated as plain text
+func Detect(content string) string {
+    // chroma has no analyser for JSON, but it is easy to recognise
+    trimmed := strings.TrimSpace(content)
+    if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+        if json.Valid([]byte(trimmed)) {
+            return "json"
+        }
+    }
+
+    lexer := lexers.Analyse(content)
+    if lexer == nil {
+        return Plaintext
+    }
+
+    name := lexer.Config().Name
+    for _, l := range Languages {
+        if lexers.Get(l.Name) != nil && lexers.Get(l.Name).Config().Name == name {
+            return l.Name
+        }
+    }
+
+    return Plaintext
+}
+
+// the formatter writes CSS classes rather than inline styles so that the
+// Content-Security-Policy does not have to allow 'unsafe-inline'
+var formatter = html.New(html.WithClasses(true), html.TabWidth(4))
+
+var style = styles.Get("github")
+
+// HTML returns content as syntax highlighted HTML for the given language,
+// wrapped in <pre><code> elements. All of the content is escaped by the
+// formatter, so the result is safe to include in a page
+func HTML(content, language string) (template.HTML, error) {
+    lexer := lexers.Get(language)
+    if lexer == nil {
+        lexer = lexers.Fallback
+    }
+    lexer = chroma.Coalesce(lexer)
+
+    iterator, err := lexer.Tokenise(nil, content)
+    if err != nil {
+        return "", err
+    }
+
+    var buf bytes.Buffer
+
+    err = formatter.Format(&buf, style, iterator)
+    if err != nil {
+        return "", err
+    }
+
+    return template.HTML(buf.String()), nil
+}
+
+// ui/static/css/chroma.css is the output of WriteCSS, and TestCSS fails if
+// the two drift apart, such as when the style or chroma itself changes
+//go:generate go test -run ^TestCSS$ -update
+
+// WriteCSS writes the stylesheet for the classes used by HTML
+func WriteCSS(w io.Writer) error {
+    return formatter.WriteCSS(w, style)
+}
@@ -0,0 +1,92 @@
+package syntax
+
+import (
+    "bytes"
+    "flag"
+    "os"
+    "strings"
+    "testing"
+
+    "github.com/j-clemons/snippetbox/internal/assert"
+)
+
+// update makes TestCSS rewrite the stylesheet instead of checking it
+var update = flag.Bool("update", false, "rewrite ui/static/css/chroma.css with the output of WriteCSS")
+
+// the stylesheet served for highlighted snippets
+const cssPath = "../../ui/static/css/chroma.css"
+
+func TestCSS(t *testing.T) {
+    var buf bytes.Buffer
+
+    err := WriteCSS(&buf)
+    assert.NilError(t, err)
+
+    if *update {
+        err = os.WriteFile(cssPath, buf.Bytes(), 0644)
+        assert.NilError(t, err)
+    }
+
+    css, err := os.ReadFile(cssPath)
+    assert.NilError(t, err)
+
+    if !bytes.Equal(css, buf.Bytes()) {
+        t.Errorf("%s is out of date; run go generate ./internal/syntax", cssPath)
+    }
+}
+
+func TestDetect(t *testing.T) {
+    tests := []struct {
+        name    string
+        content string
+        want    string
+    }{
+        {
+            name:    "Go",
+            content: "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n",
+            want:    "go",
+        },
+        {
+            name:    "Bash",
+            content: "#!/bin/bash\necho hi\n",
+            want:    "bash",
+        },
+        {
+            name:    "JSON",
+            content: `{"name": "snippetbox", "tags": ["go"]}`,
+            want:    "json",
+        },
+        {
+            name:    "Prose",
+            content: "An old silent pond...",
+            want:    Plaintext,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            assert.Equal(t, Detect(tt.content), tt.want)
+        })
+    }
+}
+
+func TestHTML(t *testing.T) {
+    out, err := HTML("<script>alert(1)</script>", "html")
+    assert.NilError(t, err)
+
+    s := string(out)
+    assert.StringContains(t, s, `<pre class="chroma">`)
+    assert.Equal(t, strings.Contains(s, "<script>"), false)
+    assert.Equal(t, strings.Contains(s, "style="), false)
+
+    // unknown languages fall back to plain text rather than failing
+    out, err = HTML("An old silent pond...", "klingon")
+    assert.NilError(t, err)
+    assert.StringContains(t, string(out), "An old silent pond...")
+}
+
+func TestExtension(t *testing.T) {
+    assert.Equal(t, Extension("go"), ".go")
+    assert.Equal(t, Extension(Markdown), ".md")
+    assert.Equal(t, Extension("klingon"), ".txt")
+}